@@ -33,7 +33,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	b := controller.New(c.SynthOS.Controller, nil)
+	b := controller.New(c.SynthOS, nil)
 	err = b.DeleteAllCommands()
 	if err != nil {
 		log.Panic().Err(err).Msg("Error deleting all commands")
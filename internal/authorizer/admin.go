@@ -0,0 +1,18 @@
+package authorizer
+
+import (
+	"context"
+)
+
+// Admin is an Authorizer that allows the operation if the user requesting the operation is the SynthOS admin.
+type Admin struct {
+	// ID is the user ID of the admin. If empty, nobody is the admin.
+	ID string
+}
+
+var _ Authorizer = (*Admin)(nil)
+
+// Authorized returns if the given user is the admin.
+func (a Admin) Authorized(_ context.Context, r Request) (bool, error) {
+	return a.ID != "" && a.ID == r.User.ID, nil
+}
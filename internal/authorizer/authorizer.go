@@ -7,5 +7,32 @@ import (
 )
 
 type Authorizer interface {
-	Authorized(ctx context.Context, owner string, u *discordgo.User) (bool, error)
+	Authorized(ctx context.Context, r Request) (bool, error)
+}
+
+// Request is a request by a user to perform an operation on a synth.
+type Request struct {
+	// Owner is the ID of the user that owns the synth.
+	Owner string
+	// User is the user requesting the operation.
+	User *discordgo.User
+	// GuildID is the guild the operation was requested in, or empty if it was not requested in a guild.
+	GuildID string
+	// Member is the user requesting the operation as a member of the guild, or nil if it was not requested in a guild.
+	Member *discordgo.Member
+	// Command is the full name of the command being used, if the operation is a command.
+	Command string
+	// Autocomplete is set if suggestions for the command's options are being requested, rather than the command being
+	// used.
+	Autocomplete bool
+}
+
+// NewRequest creates a Request for an operation requested through an interaction.
+func NewRequest(owner string, u *discordgo.User, i *discordgo.Interaction) Request {
+	return Request{
+		Owner:   owner,
+		User:    u,
+		GuildID: i.GuildID,
+		Member:  i.Member,
+	}
 }
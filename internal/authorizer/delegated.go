@@ -0,0 +1,23 @@
+package authorizer
+
+import (
+	"context"
+)
+
+// HandlerLookup looks up whether a user has been granted control over a synth by its owner.
+type HandlerLookup interface {
+	IsHandler(ctx context.Context, userID string) (bool, error)
+}
+
+// Delegated is an Authorizer that allows the operation if the user requesting the operation has been granted control
+// over the synth by its owner.
+type Delegated struct {
+	Handlers HandlerLookup
+}
+
+var _ Authorizer = (*Delegated)(nil)
+
+// Authorized returns if the given user is a handler of the synth.
+func (d Delegated) Authorized(ctx context.Context, r Request) (bool, error) {
+	return d.Handlers.IsHandler(ctx, r.User.ID)
+}
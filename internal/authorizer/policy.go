@@ -0,0 +1,73 @@
+package authorizer
+
+import (
+	"context"
+)
+
+// Effect is the effect of a Decision.
+type Effect int
+
+const (
+	// Abstain leaves the decision to later Policies.
+	Abstain Effect = iota
+	// Allow allows the operation.
+	Allow
+	// Deny denies the operation.
+	Deny
+)
+
+// Decision is the result of evaluating a Policy.
+type Decision struct {
+	Effect Effect
+	// Reason identifies why the decision was made, such as which Policy made it.
+	Reason string
+}
+
+// Policy decides whether an operation is allowed.
+type Policy interface {
+	Decide(ctx context.Context, r Request) (Decision, error)
+}
+
+type allowIf struct {
+	reason string
+	auth   Authorizer
+}
+
+// AllowIf creates a Policy that allows the operation if the Authorizer authorizes it, and abstains otherwise.
+func AllowIf(reason string, a Authorizer) Policy {
+	return allowIf{reason: reason, auth: a}
+}
+
+func (p allowIf) Decide(ctx context.Context, r Request) (Decision, error) {
+	ok, err := p.auth.Authorized(ctx, r)
+	if err != nil || !ok {
+		return Decision{Effect: Abstain}, err
+	}
+	return Decision{Effect: Allow, Reason: p.reason}, nil
+}
+
+// Chain is an ordered list of Policies. The first Policy that does not abstain makes the decision. If every Policy
+// abstains, the operation is denied.
+type Chain []Policy
+
+var _ Policy = (Chain)(nil)
+var _ Authorizer = (Chain)(nil)
+
+func (c Chain) Decide(ctx context.Context, r Request) (Decision, error) {
+	for _, p := range c {
+		d, err := p.Decide(ctx, r)
+		if err != nil {
+			return Decision{Effect: Deny}, err
+		}
+		if d.Effect != Abstain {
+			return d, nil
+		}
+	}
+	return Decision{Effect: Deny, Reason: "no policy allowed the operation"}, nil
+}
+
+// Authorized returns if the chain allows the operation, so that a Chain can be used anywhere an Authorizer can.
+func (c Chain) Authorized(ctx context.Context, r Request) (bool, error) {
+	d, err := c.Decide(ctx, r)
+	return d.Effect == Allow, err
+}
@@ -0,0 +1,104 @@
+package authorizer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+)
+
+// fixed is a Policy that always makes the same decision, and records whether it was consulted.
+type fixed struct {
+	d      authorizer.Decision
+	err    error
+	called *bool
+}
+
+func (p fixed) Decide(context.Context, authorizer.Request) (authorizer.Decision, error) {
+	*p.called = true
+	return p.d, p.err
+}
+
+// errAuthorizer is an Authorizer that always fails.
+type errAuthorizer struct{ err error }
+
+func (a errAuthorizer) Authorized(context.Context, authorizer.Request) (bool, error) {
+	return false, a.err
+}
+
+func TestChain(t *testing.T) {
+	errBroken := errors.New("broken")
+	allow := authorizer.Decision{Effect: authorizer.Allow, Reason: "allow"}
+	deny := authorizer.Decision{Effect: authorizer.Deny, Reason: "deny"}
+	abstain := authorizer.Decision{Effect: authorizer.Abstain}
+
+	for _, tc := range []struct {
+		name     string
+		policies []authorizer.Decision
+		// broken, if set, is an AllowIf policy with a failing Authorizer placed before the other policies.
+		broken bool
+		want   authorizer.Decision
+		// consulted is how many of the policies are consulted.
+		consulted int
+		wantErr   error
+	}{
+		{name: "first allow wins", policies: []authorizer.Decision{abstain, allow, deny}, want: allow, consulted: 2},
+		{name: "deny short-circuits", policies: []authorizer.Decision{deny, allow}, want: deny, consulted: 1},
+		{
+			name: "all abstain", policies: []authorizer.Decision{abstain, abstain},
+			want:      authorizer.Decision{Effect: authorizer.Deny, Reason: "no policy allowed the operation"},
+			consulted: 2,
+		},
+		{name: "empty", want: authorizer.Decision{Effect: authorizer.Deny, Reason: "no policy allowed the operation"}},
+		{
+			name: "authorizer error", policies: []authorizer.Decision{allow}, broken: true,
+			want: authorizer.Decision{Effect: authorizer.Deny}, wantErr: errBroken,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var c authorizer.Chain
+			if tc.broken {
+				c = append(c, authorizer.AllowIf("broken", errAuthorizer{errBroken}))
+			}
+			called := make([]bool, len(tc.policies))
+			for i, d := range tc.policies {
+				c = append(c, fixed{d: d, called: &called[i]})
+			}
+
+			r := authorizer.Request{Owner: "owner", User: &discordgo.User{ID: "user"}}
+			d, err := c.Decide(context.Background(), r)
+			if d != tc.want || !errors.Is(err, tc.wantErr) {
+				t.Errorf("got %+v, %v, want %+v, %v", d, err, tc.want, tc.wantErr)
+			}
+			for i, ok := range called {
+				if want := i < tc.consulted; ok != want {
+					t.Errorf("policy %d consulted: %v, want %v", i, ok, want)
+				}
+			}
+
+			ok, err := c.Authorized(context.Background(), r)
+			if want := tc.want.Effect == authorizer.Allow; ok != want || !errors.Is(err, tc.wantErr) {
+				t.Errorf("Authorized returned %v, %v, want %v, %v", ok, err, want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowIf(t *testing.T) {
+	p := authorizer.AllowIf("owner", authorizer.Self{})
+	for _, tc := range []struct {
+		user string
+		want authorizer.Decision
+	}{
+		{"owner", authorizer.Decision{Effect: authorizer.Allow, Reason: "owner"}},
+		{"someone", authorizer.Decision{Effect: authorizer.Abstain}},
+	} {
+		d, err := p.Decide(context.Background(), authorizer.Request{Owner: "owner", User: &discordgo.User{ID: tc.user}})
+		if err != nil || d != tc.want {
+			t.Errorf("%s: got %+v, %v, want %+v", tc.user, d, err, tc.want)
+		}
+	}
+}
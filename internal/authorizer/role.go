@@ -0,0 +1,45 @@
+package authorizer
+
+import (
+	"context"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// RoleLookup looks up the role that has been configured to control a synth in a guild.
+type RoleLookup interface {
+	// HandlerRole gets the ID of the role, or empty if no role has been configured.
+	HandlerRole(ctx context.Context, guildID string) (string, error)
+}
+
+// GuildRole is an Authorizer that allows the operation if the user requesting the operation is a moderator of the
+// guild it was requested in: they have any of the given permissions, or the role configured to control the synth in
+// that guild. Operations requested outside a guild are never allowed.
+type GuildRole struct {
+	// Permissions are the guild permissions, any of which allow the operation. Administrators are always allowed.
+	Permissions int64
+	Roles       RoleLookup
+}
+
+var _ Authorizer = (*GuildRole)(nil)
+
+// Authorized returns if the given user is a moderator of the guild.
+func (g GuildRole) Authorized(ctx context.Context, r Request) (bool, error) {
+	if r.GuildID == "" || r.Member == nil {
+		return false, nil
+	}
+
+	if r.Member.Permissions&(g.Permissions|discordgo.PermissionAdministrator) != 0 {
+		return true, nil
+	}
+
+	if g.Roles == nil {
+		return false, nil
+	}
+	role, err := g.Roles.HandlerRole(ctx, r.GuildID)
+	if err != nil {
+		return false, err
+	}
+	return role != "" && slices.Contains(r.Member.Roles, role), nil
+}
@@ -2,8 +2,6 @@ package authorizer
 
 import (
 	"context"
-
-	"github.com/bwmarrin/discordgo"
 )
 
 // Self is a simple Authorizer that allows the operation if and only if the user requestion the operation is the owner
@@ -13,6 +11,6 @@ type Self struct{}
 var _ Authorizer = (*Self)(nil)
 
 // Authorized returns if the given user is the user that owns the synth.
-func (Self) Authorized(_ context.Context, owner string, u *discordgo.User) (bool, error) {
-	return owner == u.ID, nil
+func (Self) Authorized(_ context.Context, r Request) (bool, error) {
+	return r.Owner == r.User.ID, nil
 }
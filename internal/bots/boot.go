@@ -0,0 +1,22 @@
+package bots
+
+// BootStage is a step in starting a Synth. They are reported as they are reached, so that the owner can follow along
+// while their Synth starts.
+type BootStage string
+
+const (
+	BootTokenValidated     BootStage = "token_validated"
+	BootConnected          BootStage = "connected"
+	BootCommandsRegistered BootStage = "commands_registered"
+	BootReady              BootStage = "ready"
+)
+
+// BootProgress is called when a Synth reaches each BootStage. A nil BootProgress ignores them.
+type BootProgress func(stage BootStage)
+
+// Report reports that a stage was reached.
+func (p BootProgress) Report(stage BootStage) {
+	if p != nil {
+		p(stage)
+	}
+}
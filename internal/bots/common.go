@@ -1,14 +1,19 @@
 package bots
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/discord"
 )
 
 type Common struct{}
 
-func (*Common) InteractionSimpleTextResponse(s *discordgo.Session, i *discordgo.Interaction, msg string) error {
+func (*Common) InteractionSimpleTextResponse(s discord.Session, i *discordgo.Interaction, msg string) error {
 	// was a channel interaction?
 	var flags discordgo.MessageFlags
 	if i.Member != nil {
@@ -28,3 +33,46 @@ func (*Common) InteractionSimpleTextResponse(s *discordgo.Session, i *discordgo.
 	}
 	return nil
 }
+
+// InteractionDeferredResponse acknowledges an interaction that will take a while to handle, so that Discord shows that
+// the bot is thinking. The response is filled in later by InteractionEditTextResponse.
+func (*Common) InteractionDeferredResponse(s discord.Session, i *discordgo.Interaction) error {
+	var flags discordgo.MessageFlags
+	if i.Member != nil {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: flags,
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("interaction response: %w", err)
+	}
+	return nil
+}
+
+// InteractionEditTextResponse replaces the response to an interaction with a simple message.
+func (*Common) InteractionEditTextResponse(s discord.Session, i *discordgo.Interaction, msg string) error {
+	_, err := s.InteractionResponseEdit(i, &discordgo.WebhookEdit{
+		Content: &msg,
+	})
+
+	if err != nil {
+		return fmt.Errorf("interaction response edit: %w", err)
+	}
+	return nil
+}
+
+// Recover logs a panic instead of letting it crash SynthOS, taking down every bot. It must be deferred at the start of
+// each event handler, as handlers run in their own goroutines.
+func Recover(ctx context.Context) {
+	if r := recover(); r != nil {
+		log.Ctx(ctx).Error().
+			Interface("panic", r).
+			Str("stack", string(debug.Stack())).
+			Msg("Recovered from panic in event handler")
+	}
+}
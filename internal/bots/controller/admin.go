@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+	"github.com/ajanata/synthos/internal/bots"
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+const reasonAdmin = "admin"
+
+func (b *Bot) buildAdminCommands() {
+	tr := i18n.Default()
+	admin := b.cmdGroup.Command("admin").
+		Localize(tr, "commands.admin").
+		Handler(b.adminHandler).
+		Policy(authorizer.AllowIf(reasonAdmin, authorizer.Admin{ID: b.adminID})).
+		Build()
+	pause := admin.Subcommand("pause").
+		Localize(tr, "commands.admin.pause").
+		Handler(b.adminPauseHandler).
+		Build()
+	pause.Option("user").
+		Localize(tr, "commands.admin.pause.user").
+		Type(discordgo.ApplicationCommandOptionUser).
+		Required().
+		Build()
+	resume := admin.Subcommand("resume").
+		Localize(tr, "commands.admin.resume").
+		Handler(b.adminResumeHandler).
+		Build()
+	resume.Option("user").
+		Localize(tr, "commands.admin.resume.user").
+		Type(discordgo.ApplicationCommandOptionUser).
+		Required().
+		Build()
+	status := admin.Subcommand("status").
+		Localize(tr, "commands.admin.status").
+		Handler(b.adminStatusHandler).
+		Build()
+	status.Option("user").
+		Localize(tr, "commands.admin.status.user").
+		Type(discordgo.ApplicationCommandOptionUser).
+		Build()
+}
+
+func (b *Bot) adminHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Warn().Msg("admin handler called")
+	return b.InteractionSimpleTextResponse(s, i.Interaction, "This shouldn't be reachable")
+}
+
+func (b *Bot) adminPauseHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("admin pause handler")
+
+	target, err := opts.User("user")
+	if err != nil {
+		return err
+	}
+	return b.pauseOrResume(ctx, s, i, target, false, "admin", target.ID)
+}
+
+func (b *Bot) adminResumeHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("admin resume handler")
+
+	target, err := opts.User("user")
+	if err != nil {
+		return err
+	}
+	return b.pauseOrResume(ctx, s, i, target, true, "admin", target.ID)
+}
+
+func (b *Bot) adminStatusHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("admin status handler")
+
+	if !opts.Has("user") {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, statusSummary(i.Locale, b.synther.SynthStatuses(ctx)))
+	}
+	target, err := opts.User("user")
+	if err != nil {
+		return err
+	}
+
+	var content string
+	status, err := b.synther.SynthStatus(ctx, target)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "admin.no_synth", target.ID)
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Str("target_id", target.ID).Msg("error getting synth status")
+		content = i18n.T(i.Locale, "admin.status_failed", target.ID)
+	} else {
+		content = i18n.T(i.Locale, "admin.status", target.ID, describeStatus(i.Locale, status))
+		content += statusDetails(i.Locale, status)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+}
+
+// statusSummary counts the Synths in each state, and lists the ones that aren't online, as much as fits in a message.
+func statusSummary(locale discordgo.Locale, statuses map[string]bots.SynthStatus) string {
+	counts := make(map[bots.SynthState]int)
+	var unhealthy []string
+	for uid, status := range statuses {
+		counts[status.State]++
+		if status.State != bots.SynthReady {
+			unhealthy = append(unhealthy, uid)
+		}
+	}
+	slices.Sort(unhealthy)
+
+	content := i18n.T(locale, "admin.status_summary",
+		counts[bots.SynthReady], counts[bots.SynthDegraded], counts[bots.SynthStarting], counts[bots.SynthFailed])
+	for j, uid := range unhealthy {
+		status := statuses[uid]
+		line := "\n" + i18n.T(locale, "admin.status", uid, describeStatus(locale, status)) + statusDetails(locale, status)
+		more := "\n" + i18n.T(locale, "admin.status_more", len(unhealthy)-j)
+		if len(content)+len(line)+len(more) > speech.MaxMessageLength {
+			content += more
+			break
+		}
+		content += line
+	}
+	return content
+}
@@ -12,16 +12,20 @@ import (
 	"github.com/ajanata/synthos/internal/command"
 	"github.com/ajanata/synthos/internal/config"
 	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
 )
 
 type Bot struct {
 	bots.Common
 
-	token string
+	token   string
+	adminID string
 
 	synther SynthCRUD
 
 	d *discordgo.Session
+	// session is d, or a fake in tests.
+	session discord.Session
 
 	cmdGroup *command.Group
 }
@@ -29,12 +33,20 @@ type Bot struct {
 type SynthCRUD interface {
 	CreateSynth(ctx context.Context, u *discordgo.User, token string) error
 	GetSynth(ctx context.Context, u *discordgo.User) (*database.Synth, error)
-	StartSynth(ctx context.Context, u *discordgo.User) error
+	// StartSynth starts a user's Synth, reporting its progress to progress, which may be nil.
+	StartSynth(ctx context.Context, u *discordgo.User, progress bots.BootProgress) error
+	DeleteSynth(ctx context.Context, u *discordgo.User) error
+	UpdateToken(ctx context.Context, u *discordgo.User, token string, rebind bool) error
+	PauseSynth(ctx context.Context, u *discordgo.User) error
+	ResumeSynth(ctx context.Context, u *discordgo.User) error
+	SynthStatus(ctx context.Context, u *discordgo.User) (bots.SynthStatus, error)
+	SynthStatuses(ctx context.Context) map[string]bots.SynthStatus
 }
 
-func New(c config.ControllerBot, synther SynthCRUD) *Bot {
+func New(c config.SynthOS, synther SynthCRUD) *Bot {
 	return &Bot{
-		token:   c.Token,
+		token:   c.Controller.Token,
+		adminID: c.AdminID,
 		synther: synther,
 	}
 }
@@ -54,7 +66,7 @@ func (b *Bot) Start() error {
 	// TODO intents
 
 	log.Ctx(ctx).Trace().Msg("Adding handlers")
-	b.d.AddHandler(b.cmdGroup.Handler)
+	b.d.AddHandler(discord.Handler(b.cmdGroup.Handler))
 	b.d.AddHandler(b.connectHandler)
 	b.d.AddHandler(b.disconnectHandler)
 
@@ -67,7 +79,7 @@ func (b *Bot) Start() error {
 		return fmt.Errorf("opening Discord session: %w", err)
 	}
 
-	err = b.cmdGroup.Register(ctx, b.d)
+	err = b.cmdGroup.Register(ctx, b.session)
 	if err != nil {
 		return fmt.Errorf("registering commands: %w", err)
 	}
@@ -89,6 +101,7 @@ func (b *Bot) setup() error {
 	}
 
 	b.d = s
+	b.session = discord.Wrap(s)
 
 	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
 		log.Info().
@@ -4,126 +4,377 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ajanata/synthos/internal/bots"
 	"github.com/ajanata/synthos/internal/command"
 	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
 )
 
+// setupDeleteButton is the prefix of the custom IDs of the buttons that confirm or cancel /setup delete. Its arguments
+// are the ID of the user deleting their Synth, and whether they confirmed it.
+const setupDeleteButton = "setup_delete"
+
 func (b *Bot) buildCommands(ctx context.Context) {
 	log.Ctx(ctx).Trace().Msg("Building commands")
 
-	b.cmdGroup = command.NewGroup()
+	b.cmdGroup = command.NewGroup().Use(command.Timing())
+	tr := i18n.Default()
 	setup := b.cmdGroup.Command("setup").
-		Description("Set up a new Synth for your account").
+		Localize(tr, "commands.setup").
 		Handler(b.setupHandler).
 		Build()
 	setup.Subcommand("start").
-		Description("Start creating a Synth instance").
+		Localize(tr, "commands.setup.start").
 		Handler(b.setupStartHandler).
 		Build()
 	token := setup.Subcommand("token").
-		Description("Set token for new Synth instance").
+		Localize(tr, "commands.setup.token").
 		Handler(b.setupTokenHandler).
 		Build()
 	token.Option("token").
-		Description("Discord App Token").
+		Localize(tr, "commands.setup.token.token").
 		Type(discordgo.ApplicationCommandOptionString).
 		Required().
 		Build()
 	setup.Subcommand("link").
-		Description("Get link for server admins to add Synth to a server, and you to add to your account").
+		Localize(tr, "commands.setup.link").
 		Handler(b.setupLinkHandler).
 		Build()
+	updateToken := setup.Subcommand("update-token").
+		Localize(tr, "commands.setup.update-token").
+		Handler(b.setupUpdateTokenHandler).
+		Build()
+	updateToken.Option("token").
+		Localize(tr, "commands.setup.update-token.token").
+		Type(discordgo.ApplicationCommandOptionString).
+		Required().
+		Build()
+	updateToken.Option("rebind").
+		Localize(tr, "commands.setup.update-token.rebind").
+		Type(discordgo.ApplicationCommandOptionBoolean).
+		Build()
+	setup.Subcommand("pause").
+		Localize(tr, "commands.setup.pause").
+		Handler(b.setupPauseHandler).
+		Build()
+	setup.Subcommand("resume").
+		Localize(tr, "commands.setup.resume").
+		Handler(b.setupResumeHandler).
+		Build()
+	setup.Subcommand("status").
+		Localize(tr, "commands.setup.status").
+		Handler(b.setupStatusHandler).
+		Build()
+	setup.Subcommand("delete").
+		Localize(tr, "commands.setup.delete").
+		Handler(b.setupDeleteHandler).
+		Build()
+	b.cmdGroup.Component(setupDeleteButton).
+		Handler(b.setupDeleteButtonHandler).
+		Build()
+
+	b.buildHandlerCommands()
+	b.buildAdminCommands()
 }
 
-const setupStartMessage = `Hi! This will be formatted better later. For now, deal with it. :sunglasses:
-
-1. Go to https://discord.com/developers/applications and click New Application.
-2. Give it a name that is meaningful to you. Maybe ` + "`<your drone identifier>'s SynthOS`" + `. Check the box and hit Create.
-3. You can set the icon, display name, and profile information now if you wish, or do it later.
-4. Click the Installation tab and make sure Install Link is set to Discord Provided Link.
-5. Under Default Install Settings, add "bot" to Guild Install, and select the following permissions:
-  * Change Nickname
-  * Create Polls
-  * Create Public Threads
-  * Embed Links
-  * Manage Messages
-  * Manage Nicknames
-  * Manage Threads
-  * Send Messages
-  * Send Messages in Threads
-(This list may change in the future, if something seems like it's not working, send this start command again to see if the list has changed and go update it if needed, and get any server admins to update it too, which might require removing the integration and adding it again.)
-6. Click Save Changes.
-7. Click the Bot tab and set its username to what you'd like. Maybe ` + "`<your drone identifier>`" + `. Make sure Public Bot is on, if you want it added to servers you are not an admin on. Turn on all 3 options under Privileged Gateway Intents. Click Save Changes.
-8. Click Reset Token back up nearer the top, and confirm that you want to do it. Copy that token, you'll need it in the next step. You may wish to save it in a secure location, too, as you won't be able to see it again.
-9. Run the ` + "`/setup token <token>` command, where `<token>`" + ` is the value you just copied.
-`
-
-func (b *Bot) setupStartHandler(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+func (b *Bot) setupStartHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
 	log.Ctx(ctx).Info().Msg("setup start handler")
 
-	return b.InteractionSimpleTextResponse(s, i.Interaction, setupStartMessage)
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.start"))
 }
 
-func (b *Bot) setupTokenHandler(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+func (b *Bot) setupTokenHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
 	log.Ctx(ctx).Info().Msg("setup token handler")
 
-	options := i.ApplicationCommandData().Options
-	var content string
+	token, err := opts.String("token")
+	if err != nil {
+		return err
+	}
 
-	// TODO make this better
-	err := b.synther.CreateSynth(ctx, u, options[0].Options[0].StringValue())
+	// validating the token and booting the Synth both take longer than Discord waits for a response
+	err = b.InteractionDeferredResponse(s, i.Interaction)
+	if err != nil {
+		return err
+	}
+
+	err = b.synther.CreateSynth(ctx, u, token)
 	if errors.Is(err, database.ErrAlreadyExists) {
-		content = "You already have a Synth instance. You must delete it (TODO) before you can make a new one. If you changed the token, TODO (but for now, delete it (TODO) and make a new one)."
-		goto out
+		return b.InteractionEditTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.already_exists"))
 	} else if errors.Is(err, ErrInvalidToken) {
-		content = "The Discord token is invalid."
-		goto out
+		return b.InteractionEditTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.invalid_token"))
 	} else if err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg("error creating synth")
-		content = "Unknown error when trying to create Synth instance."
-		goto out
+		return b.InteractionEditTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.create_failed"))
 	}
 
-	err = b.InteractionSimpleTextResponse(s, i.Interaction, "Your Synth has been created, it is now booting! TODO follow up message, but for now, Run `/setup server-link` next.")
-	if err != nil {
-		log.Ctx(ctx).Err(err).Msg("error sending response")
+	go b.bootSynth(ctx, s, u, i)
+	return nil
+}
+
+// bootSynth starts a newly created Synth, updating the response to the interaction that created it as it progresses.
+func (b *Bot) bootSynth(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) {
+	defer bots.Recover(ctx)
+
+	progress := func(stage bots.BootStage) {
+		err := b.InteractionEditTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.boot."+string(stage)))
+		if err != nil {
+			// the Synth is still booting, so it can be reported at the end
+			log.Ctx(ctx).Err(err).Str("stage", string(stage)).Msg("error reporting boot progress")
+		}
 	}
+	progress(bots.BootTokenValidated)
 
-	// TODO do this in a goroutine and update the previous response when it's done
-	err = b.synther.StartSynth(ctx, u)
+	var content string
+	err := b.synther.StartSynth(ctx, u, progress)
 	if err != nil {
 		log.Ctx(ctx).Err(err).Msg("error starting synth")
-		content = "An internal error occurred while booting your Synth."
+		content = i18n.T(i.Locale, "setup.boot_failed")
 	} else {
-		content = "Your Synth has been created! Run `/setup link` next."
+		content = i18n.T(i.Locale, "setup.created")
 	}
 
-out:
-	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+	err = b.InteractionEditTextResponse(s, i.Interaction, content)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error sending response")
+	}
 }
 
-func (b *Bot) setupLinkHandler(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+func (b *Bot) setupLinkHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
 	log.Ctx(ctx).Info().Msg("setup link handler")
 
 	var content string
 	link, err := b.getServerLink(ctx, u)
 	if errors.Is(err, database.ErrNotFound) {
-		content = "You do not have a Synth instance."
+		content = i18n.T(i.Locale, "setup.no_synth")
 	} else if err != nil {
 		log.Ctx(ctx).Err(err).Msg("error getting synth")
-		content = "Unknown error when trying to get Synth instance."
+		content = i18n.T(i.Locale, "setup.link_failed")
+	} else {
+		content = i18n.T(i.Locale, "setup.link", link)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+}
+
+func (b *Bot) setupUpdateTokenHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("setup update token handler")
+
+	token, err := opts.String("token")
+	if err != nil {
+		return err
+	}
+	rebind, err := opts.BoolOr("rebind", false)
+	if err != nil {
+		return err
+	}
+
+	// validating the token and reconnecting takes longer than Discord waits for a response
+	err = b.InteractionDeferredResponse(s, i.Interaction)
+	if err != nil {
+		return err
+	}
+
+	var content string
+	err = b.synther.UpdateToken(ctx, u, token, rebind)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "setup.no_synth")
+	} else if errors.Is(err, ErrInvalidToken) {
+		content = i18n.T(i.Locale, "setup.invalid_token")
+	} else if errors.Is(err, ErrDifferentApplication) {
+		content = i18n.T(i.Locale, "setup.different_application")
+	} else if errors.Is(err, ErrTokenNotSwapped) {
+		content = i18n.T(i.Locale, "setup.token_not_swapped")
+	} else if errors.Is(err, ErrUnableToStartSynth) {
+		content = i18n.T(i.Locale, "setup.boot_failed")
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error updating token")
+		content = i18n.T(i.Locale, "setup.update_failed")
+	} else {
+		content = i18n.T(i.Locale, "setup.token_updated")
+	}
+
+	return b.InteractionEditTextResponse(s, i.Interaction, content)
+}
+
+func (b *Bot) setupPauseHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("setup pause handler")
+	return b.pauseOrResume(ctx, s, i, u, false, "setup")
+}
+
+func (b *Bot) setupResumeHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("setup resume handler")
+	return b.pauseOrResume(ctx, s, i, u, true, "setup")
+}
+
+// pauseOrResume pauses or resumes the Synth of target, replying with the messages in the given section of the catalog,
+// which are formatted with args.
+func (b *Bot) pauseOrResume(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, target *discordgo.User, resume bool, section string, args ...any) error {
+	// starting a Synth takes longer than Discord waits for a response
+	err := b.InteractionDeferredResponse(s, i.Interaction)
+	if err != nil {
+		return err
+	}
+
+	if resume {
+		err = b.synther.ResumeSynth(ctx, target)
+	} else {
+		err = b.synther.PauseSynth(ctx, target)
+	}
+
+	var key string
+	if errors.Is(err, database.ErrNotFound) {
+		key = "no_synth"
+	} else if errors.Is(err, ErrAlreadyPaused) {
+		key = "already_paused"
+	} else if errors.Is(err, ErrNotPaused) {
+		key = "not_paused"
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Str("target_id", target.ID).Bool("resume", resume).Msg("error pausing or resuming synth")
+		key = "pause_failed"
+		if resume {
+			key = "resume_failed"
+		}
+	} else if resume {
+		key = "resumed"
+	} else {
+		key = "paused"
+	}
+
+	return b.InteractionEditTextResponse(s, i.Interaction, i18n.T(i.Locale, section+"."+key, args...))
+}
+
+func (b *Bot) setupStatusHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("setup status handler")
+
+	var content string
+	status, err := b.synther.SynthStatus(ctx, u)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "setup.no_synth")
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error getting synth status")
+		content = i18n.T(i.Locale, "setup.status_failed")
 	} else {
-		content = "Give this link to an admin of each server you'd like your Synth to join: " + link + "\n\nYou should also Add to My Apps."
+		content = i18n.T(i.Locale, "setup.status", describeStatus(i.Locale, status))
+		content += statusDetails(i.Locale, status)
 	}
 
 	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
 }
 
-func (b *Bot) setupHandler(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+// describeStatus describes the state of a Synth, and how long it has been in that state.
+func describeStatus(locale discordgo.Locale, status bots.SynthStatus) string {
+	state := i18n.T(locale, "status."+string(status.State))
+	if status.Since.IsZero() {
+		return "**" + state + "**"
+	}
+	return i18n.T(locale, "status.since", state, status.Since.Unix())
+}
+
+// statusDetails explains why a Synth failed and when it will be restarted, on their own lines, or is empty if it
+// hasn't.
+func statusDetails(locale discordgo.Locale, status bots.SynthStatus) string {
+	var details string
+	if status.Err != nil {
+		details += "\n" + i18n.T(locale, "status.failures", status.Failures, status.Err)
+	}
+	if status.State == bots.SynthFailed && !status.Retry.IsZero() {
+		details += "\n" + i18n.T(locale, "status.retry", status.Retry.Unix())
+	}
+	return details
+}
+
+func (b *Bot) setupDeleteHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("setup delete handler")
+
+	_, err := b.synther.GetSynth(ctx, u)
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.no_synth"))
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error getting synth")
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.delete_failed"))
+	}
+
+	var flags discordgo.MessageFlags
+	if i.Member != nil {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: i18n.T(i.Locale, "setup.delete_confirm"),
+			Flags:   flags,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    i18n.T(i.Locale, "setup.delete"),
+							Style:    discordgo.DangerButton,
+							CustomID: command.NewCustomID(setupDeleteButton, u.ID, true).String(),
+						},
+						discordgo.Button{
+							Label:    i18n.T(i.Locale, "setup.cancel"),
+							Style:    discordgo.SecondaryButton,
+							CustomID: command.NewCustomID(setupDeleteButton, u.ID, false).String(),
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (b *Bot) setupDeleteButtonHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	log.Ctx(ctx).Info().Msg("setup delete button handler")
+
+	userID, err := id.Arg(0)
+	if err != nil {
+		return err
+	}
+	arg, err := id.Arg(1)
+	if err != nil {
+		return err
+	}
+	confirmed, err := strconv.ParseBool(arg)
+	if err != nil {
+		return fmt.Errorf("argument 1 of %s: %w", id.Prefix, err)
+	}
+	// only the user that asked to delete their Synth should see the buttons, but make sure
+	if userID != u.ID {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "setup.delete_not_yours"))
+	}
+
+	var content string
+	if !confirmed {
+		content = i18n.T(i.Locale, "setup.delete_cancelled")
+	} else {
+		err = b.synther.DeleteSynth(ctx, u)
+		if errors.Is(err, database.ErrNotFound) {
+			content = i18n.T(i.Locale, "setup.no_synth")
+		} else if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error deleting synth")
+			content = i18n.T(i.Locale, "setup.delete_failed")
+		} else {
+			content = i18n.T(i.Locale, "setup.deleted")
+		}
+	}
+
+	// replace the confirmation, so the buttons can't be pressed again
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func (b *Bot) setupHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
 	log.Ctx(ctx).Warn().Msg("setup handler called")
 	return b.InteractionSimpleTextResponse(s, i.Interaction, "This shouldn't be reachable")
 }
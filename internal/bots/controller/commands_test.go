@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/bots"
+	"github.com/ajanata/synthos/internal/config"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// fakeSynths is an in-memory SynthCRUD.
+type fakeSynths struct {
+	synths  map[string]*database.Synth
+	started []string
+	// tokens that are valid
+	tokens map[string]string
+	// statuses of the Synths that are running
+	statuses map[string]bots.SynthStatus
+	// swapFails makes running Synths fail to reconnect with a new token, so they keep the old one.
+	swapFails bool
+}
+
+func (f *fakeSynths) CreateSynth(_ context.Context, u *discordgo.User, token string) error {
+	appID, ok := f.tokens[token]
+	if !ok {
+		return ErrInvalidToken
+	}
+	if _, ok := f.synths[u.ID]; ok {
+		return database.ErrAlreadyExists
+	}
+	f.synths[u.ID] = &database.Synth{DiscordUserID: u.ID, ApplicationID: appID, Token: token, Enabled: true}
+	return nil
+}
+
+func (f *fakeSynths) GetSynth(_ context.Context, u *discordgo.User) (*database.Synth, error) {
+	synth, ok := f.synths[u.ID]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	return synth, nil
+}
+
+func (f *fakeSynths) StartSynth(_ context.Context, u *discordgo.User, progress bots.BootProgress) error {
+	for _, stage := range []bots.BootStage{bots.BootConnected, bots.BootCommandsRegistered, bots.BootReady} {
+		progress.Report(stage)
+	}
+	f.started = append(f.started, u.ID)
+	return nil
+}
+
+func (f *fakeSynths) DeleteSynth(_ context.Context, u *discordgo.User) error {
+	if _, ok := f.synths[u.ID]; !ok {
+		return database.ErrNotFound
+	}
+	delete(f.synths, u.ID)
+	return nil
+}
+
+func (f *fakeSynths) UpdateToken(_ context.Context, u *discordgo.User, token string, rebind bool) error {
+	synth, ok := f.synths[u.ID]
+	if !ok {
+		return database.ErrNotFound
+	}
+	appID, ok := f.tokens[token]
+	if !ok {
+		return ErrInvalidToken
+	} else if appID != synth.ApplicationID && !rebind {
+		return ErrDifferentApplication
+	} else if f.swapFails {
+		return ErrTokenNotSwapped
+	}
+	synth.ApplicationID = appID
+	synth.Token = token
+	return nil
+}
+
+func (f *fakeSynths) PauseSynth(_ context.Context, u *discordgo.User) error {
+	synth, ok := f.synths[u.ID]
+	if !ok {
+		return database.ErrNotFound
+	} else if !synth.Enabled {
+		return ErrAlreadyPaused
+	}
+	synth.Enabled = false
+	return nil
+}
+
+func (f *fakeSynths) ResumeSynth(_ context.Context, u *discordgo.User) error {
+	synth, ok := f.synths[u.ID]
+	if !ok {
+		return database.ErrNotFound
+	} else if synth.Enabled {
+		return ErrNotPaused
+	}
+	synth.Enabled = true
+	f.started = append(f.started, u.ID)
+	return nil
+}
+
+func (f *fakeSynths) SynthStatus(_ context.Context, u *discordgo.User) (bots.SynthStatus, error) {
+	if _, ok := f.synths[u.ID]; !ok {
+		return bots.SynthStatus{}, database.ErrNotFound
+	}
+	if status, ok := f.statuses[u.ID]; ok {
+		return status, nil
+	}
+	return bots.SynthStatus{State: bots.SynthStopped}, nil
+}
+
+func (f *fakeSynths) SynthStatuses(context.Context) map[string]bots.SynthStatus {
+	return f.statuses
+}
+
+// newTestBot makes a controller with its commands registered with a fake Discord.
+func newTestBot(t *testing.T) (*Bot, *fakeSynths, *discordtest.Session) {
+	t.Helper()
+	synths := &fakeSynths{
+		synths:   make(map[string]*database.Synth),
+		statuses: make(map[string]bots.SynthStatus),
+		tokens:   map[string]string{"good-token": "app-id", "reset-token": "app-id", "other-token": "other-app-id"},
+	}
+	b := New(config.SynthOS{AdminID: "admin-id"}, synths)
+	s := discordtest.New("controller")
+	b.session = s
+
+	b.buildCommands(t.Context())
+	err := b.cmdGroup.Register(t.Context(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, synths, s
+}
+
+func TestSetup(t *testing.T) {
+	b, synths, s := newTestBot(t)
+	u := discordtest.NewUser("drone")
+
+	// setup runs a subcommand, and returns its interaction and what it last said
+	setup := func(sub string, opts ...*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionCreate, string) {
+		t.Helper()
+		i := discordtest.Command(u, "", "setup", discordtest.Subcommand(sub, opts...))
+		b.cmdGroup.Handler(s, i)
+		if edits := s.ResponseEdits(i.ID); len(edits) > 0 {
+			return i, *edits[len(edits)-1].Content
+		}
+		r := s.Response(i.ID)
+		if r == nil || r.Data == nil {
+			t.Fatalf("/setup %s was not responded to", sub)
+		}
+		return i, r.Data.Content
+	}
+	tr := func(key string, args ...any) string {
+		return i18n.T(discordgo.EnglishUS, key, args...)
+	}
+
+	if _, got := setup("start"); got != tr("setup.start") {
+		t.Errorf("/setup start got %q", got)
+	}
+	if _, got := setup("link"); got != tr("setup.no_synth") {
+		t.Errorf("/setup link without a Synth got %q", got)
+	}
+
+	if _, got := setup("token", discordtest.String("token", "bad-token")); got != tr("setup.invalid_token") {
+		t.Errorf("/setup token with a bad token got %q", got)
+	}
+	if len(synths.synths) != 0 {
+		t.Error("a Synth was created with a bad token")
+	}
+
+	// the Synth boots in the background, editing the response as it goes
+	i, _ := setup("token", discordtest.String("token", "good-token"))
+	if _, ok := synths.synths[u.ID]; !ok {
+		t.Fatal("the Synth was not created")
+	}
+	var got []string
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		got = got[:0]
+		for _, edit := range s.ResponseEdits(i.ID) {
+			got = append(got, *edit.Content)
+		}
+		if len(got) > 0 && got[len(got)-1] == tr("setup.created") {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("/setup token said %q, want it to finish booting", got)
+		}
+	}
+	want := []string{
+		tr("setup.boot.token_validated"),
+		tr("setup.boot.connected"),
+		tr("setup.boot.commands_registered"),
+		tr("setup.boot.ready"),
+		tr("setup.created"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("/setup token said %q, want %q", got, want)
+	}
+	if len(synths.started) != 1 || synths.started[0] != u.ID {
+		t.Errorf("started %v, want the new Synth", synths.started)
+	}
+
+	if _, got := setup("token", discordtest.String("token", "good-token")); got != tr("setup.already_exists") {
+		t.Errorf("/setup token with an existing Synth got %q", got)
+	}
+	if _, got := setup("link"); !strings.Contains(got, "client_id=app-id") {
+		t.Errorf("/setup link got %q, want the Synth's install link", got)
+	}
+}
+
+func TestSetupDelete(t *testing.T) {
+	b, synths, s := newTestBot(t)
+	u := discordtest.NewUser("drone")
+	other := discordtest.NewUser("other")
+	synths.synths[u.ID] = &database.Synth{DiscordUserID: u.ID}
+
+	i := discordtest.Command(u, "guild", "setup", discordtest.Subcommand("delete"))
+	b.cmdGroup.Handler(s, i)
+	r := s.Response(i.ID)
+	if r == nil || len(r.Data.Components) != 1 {
+		t.Fatalf("/setup delete got %+v, want confirmation buttons", r)
+	}
+	var confirm, cancel string
+	for _, c := range r.Data.Components[0].(discordgo.ActionsRow).Components {
+		button := c.(discordgo.Button)
+		if button.Style == discordgo.DangerButton {
+			confirm = button.CustomID
+		} else {
+			cancel = button.CustomID
+		}
+	}
+
+	press := func(u *discordgo.User, customID string) string {
+		t.Helper()
+		i := discordtest.Button(u, "guild", customID)
+		b.cmdGroup.Handler(s, i)
+		r := s.Response(i.ID)
+		if r == nil {
+			t.Fatalf("button %s was not responded to", customID)
+		}
+		return r.Data.Content
+	}
+
+	press(u, cancel)
+	press(other, confirm)
+	if _, ok := synths.synths[u.ID]; !ok {
+		t.Fatal("the Synth was deleted without being confirmed by its owner")
+	}
+
+	if got := press(u, confirm); got != i18n.T(discordgo.EnglishUS, "setup.deleted") {
+		t.Errorf("confirming got %q", got)
+	}
+	if _, ok := synths.synths[u.ID]; ok {
+		t.Error("the Synth was not deleted")
+	}
+}
+
+func TestSetupUpdateToken(t *testing.T) {
+	b, synths, s := newTestBot(t)
+	u := discordtest.NewUser("drone")
+	synths.synths[u.ID] = &database.Synth{DiscordUserID: u.ID, ApplicationID: "app-id", Token: "good-token"}
+
+	updateToken := func(opts ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		i := discordtest.Command(u, "", "setup", discordtest.Subcommand("update-token", opts...))
+		b.cmdGroup.Handler(s, i)
+		edits := s.ResponseEdits(i.ID)
+		if len(edits) != 1 {
+			t.Fatalf("/setup update-token edited its response %d times, want 1", len(edits))
+		}
+		return *edits[0].Content
+	}
+	tr := func(key string) string {
+		return i18n.T(discordgo.EnglishUS, key)
+	}
+
+	if got := updateToken(discordtest.String("token", "bad-token")); got != tr("setup.invalid_token") {
+		t.Errorf("a bad token got %q", got)
+	}
+	if got := updateToken(discordtest.String("token", "other-token")); got != tr("setup.different_application") {
+		t.Errorf("a token for another application got %q", got)
+	}
+	if got := updateToken(discordtest.String("token", "reset-token")); got != tr("setup.token_updated") {
+		t.Errorf("a reset token got %q", got)
+	}
+	if got := synths.synths[u.ID].Token; got != "reset-token" {
+		t.Errorf("token is %q, want the reset token", got)
+	}
+
+	got := updateToken(discordtest.String("token", "other-token"), discordtest.Bool("rebind", true))
+	if got != tr("setup.token_updated") || synths.synths[u.ID].ApplicationID != "other-app-id" {
+		t.Errorf("rebinding got %q, and the application is %s", got, synths.synths[u.ID].ApplicationID)
+	}
+
+	// the Synth couldn't reconnect with the new token, so it is still using the old one
+	synths.swapFails = true
+	got = updateToken(discordtest.String("token", "good-token"), discordtest.Bool("rebind", true))
+	if got != tr("setup.token_not_swapped") || synths.synths[u.ID].Token != "other-token" {
+		t.Errorf("a failed swap got %q, and the token is %s", got, synths.synths[u.ID].Token)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	b, synths, s := newTestBot(t)
+	u := discordtest.NewUser("drone")
+	admin := &discordgo.User{ID: "admin-id", Username: "admin"}
+	synths.synths[u.ID] = &database.Synth{DiscordUserID: u.ID, Enabled: true}
+
+	run := func(by *discordgo.User, name, sub string, opts ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		i := discordtest.Command(by, "", name, discordtest.Subcommand(sub, opts...))
+		b.cmdGroup.Handler(s, i)
+		if edits := s.ResponseEdits(i.ID); len(edits) == 1 {
+			return *edits[0].Content
+		}
+		if r := s.Response(i.ID); r != nil && r.Data != nil {
+			return r.Data.Content
+		}
+		t.Fatalf("/%s %s was not responded to", name, sub)
+		return ""
+	}
+	tr := func(key string, args ...any) string {
+		return i18n.T(discordgo.EnglishUS, key, args...)
+	}
+
+	if got := run(u, "setup", "resume"); got != tr("setup.not_paused") {
+		t.Errorf("resuming a running Synth got %q", got)
+	}
+	if got := run(u, "setup", "pause"); got != tr("setup.paused") || synths.synths[u.ID].Enabled {
+		t.Errorf("pausing got %q", got)
+	}
+	if got := run(u, "setup", "pause"); got != tr("setup.already_paused") {
+		t.Errorf("pausing a paused Synth got %q", got)
+	}
+
+	// only the admin can resume other users' Synths
+	run(u, "admin", "resume", discordtest.User("user", u))
+	if synths.synths[u.ID].Enabled {
+		t.Error("a user that is not the admin used /admin")
+	}
+	if got := run(admin, "admin", "resume", discordtest.User("user", u)); got != tr("admin.resumed", u.ID) {
+		t.Errorf("admin resuming got %q", got)
+	}
+	if !synths.synths[u.ID].Enabled {
+		t.Error("the Synth was not resumed")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	b, synths, s := newTestBot(t)
+	u := discordtest.NewUser("drone")
+	other := discordtest.NewUser("other")
+	admin := &discordgo.User{ID: "admin-id", Username: "admin"}
+	synths.synths[u.ID] = &database.Synth{DiscordUserID: u.ID, Enabled: true}
+	synths.synths[other.ID] = &database.Synth{DiscordUserID: other.ID, Enabled: true}
+	since := time.Unix(1700000000, 0)
+	synths.statuses[u.ID] = bots.SynthStatus{
+		State:    bots.SynthFailed,
+		Since:    since,
+		Failures: 2,
+		Err:      errors.New("websocket: close 4004: Authentication failed."),
+		Retry:    since.Add(time.Minute),
+	}
+	synths.statuses[other.ID] = bots.SynthStatus{State: bots.SynthReady, Since: since}
+
+	run := func(by *discordgo.User, name string, sub *discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		i := discordtest.Command(by, "", name, sub)
+		b.cmdGroup.Handler(s, i)
+		r := s.Response(i.ID)
+		if r == nil || r.Data == nil {
+			t.Fatalf("/%s %s was not responded to", name, sub.Name)
+		}
+		return r.Data.Content
+	}
+
+	got := run(u, "setup", discordtest.Subcommand("status"))
+	for _, want := range []string{"failed", "4004", "<t:1700000000:R>", "<t:1700000060:R>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("/setup status got %q, want it to contain %q", got, want)
+		}
+	}
+
+	got = run(admin, "admin", discordtest.Subcommand("status"))
+	if !strings.HasPrefix(got, i18n.T(discordgo.EnglishUS, "admin.status_summary", 1, 0, 0, 1)) {
+		t.Errorf("/admin status got %q, want it to count the Synths", got)
+	}
+	if !strings.Contains(got, "<@"+u.ID+">") || strings.Contains(got, "<@"+other.ID+">") {
+		t.Errorf("/admin status got %q, want only the failed Synth listed", got)
+	}
+
+	got = run(admin, "admin", discordtest.Subcommand("status", discordtest.User("user", other)))
+	if !strings.Contains(got, "online") {
+		t.Errorf("/admin status for a user got %q", got)
+	}
+}
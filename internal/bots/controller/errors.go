@@ -6,3 +6,14 @@ import (
 
 var ErrInvalidToken = errors.New("invalid token")
 var ErrUnableToStartSynth = errors.New("unable to start synth")
+var ErrUnableToDeleteSynth = errors.New("unable to delete synth")
+var ErrUnableToUpdateToken = errors.New("unable to update token")
+
+// ErrDifferentApplication is returned when a token is for a different application than the Synth's.
+var ErrDifferentApplication = errors.New("token is for a different application")
+
+// ErrTokenNotSwapped is returned when a running Synth couldn't reconnect with a new token, so it kept the old one.
+var ErrTokenNotSwapped = errors.New("synth could not reconnect with the new token")
+var ErrUnableToPauseSynth = errors.New("unable to pause synth")
+var ErrAlreadyPaused = errors.New("synth is already paused")
+var ErrNotPaused = errors.New("synth is not paused")
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// auditEntriesShown is how many audit log entries are shown by /handler list.
+const auditEntriesShown = 10
+
+func (b *Bot) buildHandlerCommands() {
+	tr := i18n.Default()
+	handler := b.cmdGroup.Command("handler").
+		Localize(tr, "commands.handler").
+		Handler(b.handlerHandler).
+		Build()
+	grant := handler.Subcommand("grant").
+		Localize(tr, "commands.handler.grant").
+		Handler(b.handlerGrantHandler).
+		Build()
+	grant.Option("user").
+		Localize(tr, "commands.handler.grant.user").
+		Type(discordgo.ApplicationCommandOptionUser).
+		Required().
+		Build()
+	revoke := handler.Subcommand("revoke").
+		Localize(tr, "commands.handler.revoke").
+		Handler(b.handlerRevokeHandler).
+		Build()
+	revoke.Option("user").
+		Localize(tr, "commands.handler.revoke.user").
+		Type(discordgo.ApplicationCommandOptionUser).
+		Required().
+		Build()
+	handler.Subcommand("list").
+		Localize(tr, "commands.handler.list").
+		Handler(b.handlerListHandler).
+		Build()
+}
+
+func (b *Bot) handlerHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Warn().Msg("handler handler called")
+	return b.InteractionSimpleTextResponse(s, i.Interaction, "This shouldn't be reachable")
+}
+
+func (b *Bot) handlerGrantHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("handler grant handler")
+
+	target, err := opts.User("user")
+	if err != nil {
+		return err
+	}
+
+	var content string
+	synth, err := b.synther.GetSynth(ctx, u)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "setup.no_synth")
+		goto out
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error getting synth")
+		content = i18n.T(i.Locale, "setup.link_failed")
+		goto out
+	}
+
+	if target.ID == u.ID {
+		content = i18n.T(i.Locale, "handler.self")
+		goto out
+	} else if target.Bot {
+		content = i18n.T(i.Locale, "handler.bot")
+		goto out
+	}
+
+	err = synth.GrantHandler(ctx, u.ID, target.ID)
+	if errors.Is(err, database.ErrAlreadyExists) {
+		content = i18n.T(i.Locale, "handler.already_granted", target.ID)
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Str("handler_id", target.ID).Msg("error granting handler")
+		content = i18n.T(i.Locale, "handler.grant_failed")
+	} else {
+		content = i18n.T(i.Locale, "handler.granted", target.ID)
+	}
+
+out:
+	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+}
+
+func (b *Bot) handlerRevokeHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("handler revoke handler")
+
+	target, err := opts.User("user")
+	if err != nil {
+		return err
+	}
+
+	var content string
+	synth, err := b.synther.GetSynth(ctx, u)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "setup.no_synth")
+		goto out
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error getting synth")
+		content = i18n.T(i.Locale, "setup.link_failed")
+		goto out
+	}
+
+	err = synth.RevokeHandler(ctx, u.ID, target.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "handler.not_granted", target.ID)
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Str("handler_id", target.ID).Msg("error revoking handler")
+		content = i18n.T(i.Locale, "handler.revoke_failed")
+	} else {
+		content = i18n.T(i.Locale, "handler.revoked", target.ID)
+	}
+
+out:
+	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+}
+
+func (b *Bot) handlerListHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Info().Msg("handler list handler")
+
+	var content string
+	synth, err := b.synther.GetSynth(ctx, u)
+	if errors.Is(err, database.ErrNotFound) {
+		content = i18n.T(i.Locale, "setup.no_synth")
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error getting synth")
+		content = i18n.T(i.Locale, "setup.link_failed")
+	} else {
+		content, err = handlerList(ctx, i.Locale, synth)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error listing handlers")
+			content = i18n.T(i.Locale, "handler.list_failed")
+		}
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+}
+
+// handlerList formats the handlers and recent audit log of a Synth for display.
+func handlerList(ctx context.Context, locale discordgo.Locale, synth *database.Synth) (string, error) {
+	handlers, err := synth.Handlers(ctx)
+	if err != nil {
+		return "", err
+	}
+	entries, err := synth.AuditEntries(ctx, auditEntriesShown)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if len(handlers) == 0 {
+		sb.WriteString(i18n.T(locale, "handler.none"))
+	} else {
+		sb.WriteString(i18n.T(locale, "handler.list"))
+		for _, h := range handlers {
+			sb.WriteString("\n* " + i18n.T(locale, "handler.entry", h.DiscordUserID, h.CreatedAt.Unix()))
+		}
+	}
+
+	if len(entries) > 0 {
+		sb.WriteString("\n\n" + i18n.T(locale, "handler.activity"))
+		for _, e := range entries {
+			var action string
+			switch e.Action {
+			case database.AuditGrantHandler:
+				action = i18n.T(locale, "handler.audit.grant", e.ActorID, e.TargetID)
+			case database.AuditRevokeHandler:
+				action = i18n.T(locale, "handler.audit.revoke", e.ActorID, e.TargetID)
+			case database.AuditHandlerCommand:
+				action = i18n.T(locale, "handler.audit.handler_command", e.ActorID, e.Detail)
+			case database.AuditModeratorCommand:
+				action = i18n.T(locale, "handler.audit.moderator_command", e.ActorID, e.Detail)
+			case database.AuditAdminCommand:
+				action = i18n.T(locale, "handler.audit.admin_command", e.ActorID, e.Detail)
+			default:
+				action = fmt.Sprintf("<@%s> %s", e.ActorID, e.Action)
+			}
+			sb.WriteString(fmt.Sprintf("\n* <t:%d:f> %s", e.CreatedAt.Unix(), action))
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,34 @@
+package bots
+
+import (
+	"time"
+)
+
+// SynthState is the health of a running Synth.
+type SynthState string
+
+const (
+	// SynthStarting is a Synth that is connecting to Discord for the first time, or being restarted after it failed.
+	SynthStarting SynthState = "starting"
+	// SynthReady is a Synth that is connected to Discord.
+	SynthReady SynthState = "ready"
+	// SynthDegraded is a Synth that lost its connection to Discord, and is reconnecting.
+	SynthDegraded SynthState = "degraded"
+	// SynthFailed is a Synth that could not be started or reconnected. It will be restarted after a delay.
+	SynthFailed SynthState = "failed"
+	// SynthStopped is a Synth that is not running, because it is paused or was never started.
+	SynthStopped SynthState = "stopped"
+)
+
+// SynthStatus is the health of a Synth, and how it got there.
+type SynthStatus struct {
+	State SynthState
+	// Since is when the Synth entered State.
+	Since time.Time
+	// Failures is how many times in a row the Synth has failed to start or reconnect.
+	Failures int
+	// Err is why the Synth last failed, if it is failed.
+	Err error
+	// Retry is when the Synth will next be restarted, if it is failed.
+	Retry time.Time
+}
@@ -6,14 +6,22 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ajanata/synthos/internal/authorizer"
 	"github.com/ajanata/synthos/internal/bots"
 	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/config"
 	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+	"github.com/ajanata/synthos/internal/speech"
 )
 
 const commandEdit = "s;edit "
@@ -22,74 +30,212 @@ const maxProxyFileSize = 50 * 1024 * 1024
 type Bot struct {
 	bots.Common
 
-	synth *database.Synth
+	synth   *database.Synth
+	adminID string
+	// commandGuildID is the guild to register commands in, or "" to register them globally.
+	commandGuildID string
 
-	d *discordgo.Session
+	// sessionMu guards d and session, which SwapToken replaces.
+	sessionMu sync.RWMutex
+	d         *discordgo.Session
+	// session is d, or a fake in tests.
+	session discord.Session
 
 	cmdGroup *command.Group
-
-	// XXX hack
-	maxEnergy int
-	regen     int
+	// ownerOnly is the policy for commands that only the owner can use.
+	ownerOnly authorizer.Policy
+	// controllers is the policy for commands that anyone with control over the Synth can use.
+	controllers authorizer.Policy
+	// handlers is the policy for changes that apply in every guild, which guild moderators can't make.
+	handlers authorizer.Policy
+	// moderators is the policy for choosing the handler role of a guild, which the role itself doesn't allow.
+	moderators authorizer.Policy
+
+	// energyMu serializes energy updates, as messages can be handled concurrently.
+	energyMu sync.Mutex
+
+	// lockMu guards the lock state of the Synth, lockTimer, and presence.
+	lockMu sync.Mutex
+	// lockTimer releases the lock when it expires, if it has a time limit.
+	lockTimer *time.Timer
+	// presence is the presence most recently mirrored from the owner.
+	presence discordgo.UpdateStatusData
+
+	// watcher is told when the connection to Discord is lost or regained. It is nil if nothing is watching.
+	watcher func(state bots.SynthState)
+	// swapping is set while SwapToken replaces the session, which the watcher shouldn't be told about.
+	swapping atomic.Bool
 }
 
-func New(synth *database.Synth) *Bot {
+func New(c config.SynthOS, synth *database.Synth) *Bot {
 	return &Bot{
-		synth: synth,
+		synth:          synth,
+		adminID:        c.AdminID,
+		commandGuildID: c.CommandGuildID,
+		presence: discordgo.UpdateStatusData{
+			Status: string(discordgo.StatusOnline),
+		},
 	}
 }
 
-func (b *Bot) Start() error {
+// Start connects the Synth to Discord, reporting its progress to progress, which may be nil.
+func (b *Bot) Start(progress bots.BootProgress) (err error) {
 	ctx := log.With().Str("user_id", b.synth.DiscordUserID).Logger().WithContext(context.Background())
 	log.Ctx(ctx).Info().Msg("Starting synth")
 
-	err := b.setup(ctx)
+	// a mistake in building the commands shouldn't take down every other Synth
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("starting synth: %v", r)
+		}
+	}()
+
+	err = b.setup(ctx)
 	if err != nil {
 		return err
 	}
 
 	b.buildCommands(ctx)
 
+	return b.connect(ctx, progress)
+}
+
+// Watch sets a function to tell when the Synth's connection to Discord is lost or regained. It must be called before
+// Start. Lost connections are not reconnected automatically, so whatever is watching must call Reconnect.
+func (b *Bot) Watch(watcher func(state bots.SynthState)) {
+	b.watcher = watcher
+}
+
+// Reconnect reconnects to Discord after the connection was lost, resuming the session if Discord allows it.
+func (b *Bot) Reconnect() error {
+	d, _ := b.current()
+	err := d.Open()
+	if err != nil && !errors.Is(err, discordgo.ErrWSAlreadyOpen) {
+		return fmt.Errorf("reopening Discord session: %w", err)
+	}
+	return nil
+}
+
+// SwapToken replaces the Discord session with one for a new token, such as after the owner reset it. The token must
+// have already been validated. appID is the application the token is for, which can differ from the old one if the
+// owner moved their Synth to a new application. Everything else about the Synth, such as its lock, is kept. If the new
+// session can't connect, the Synth goes back to the old token, and the old session is reopened.
+func (b *Bot) SwapToken(ctx context.Context, appID, token string) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("Swapping token")
+
+	// the old session closing, and the new one connecting, are part of the swap rather than lost connections
+	b.swapping.Store(true)
+	defer b.swapping.Store(false)
+
+	// resetting the token disconnects the old session anyway, and two sessions would proxy every message twice
+	old, oldSession := b.current()
+	err := old.Close()
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("Error closing old session")
+	}
+
+	d, err := b.newSession(ctx, token)
+	if err != nil {
+		return errors.Join(err, b.restoreSession(ctx, old, oldSession))
+	}
+	oldAppID, oldToken := b.synth.ApplicationID, b.synth.Token
+	b.sessionMu.Lock()
+	b.d = d
+	b.session = discord.Wrap(d)
+	b.synth.ApplicationID = appID
+	b.synth.Token = token
+	b.sessionMu.Unlock()
+
+	// this also reschedules the lock's timer against the new session
+	err = b.connect(ctx, nil)
+	if err == nil {
+		return nil
+	}
+
+	closeErr := d.Close()
+	if closeErr != nil {
+		log.Ctx(ctx).Warn().Err(closeErr).Msg("Error closing new session")
+	}
+	b.sessionMu.Lock()
+	b.d = old
+	b.session = oldSession
+	b.synth.ApplicationID = oldAppID
+	b.synth.Token = oldToken
+	b.sessionMu.Unlock()
+	return errors.Join(err, b.restoreSession(ctx, old, oldSession))
+}
+
+// restoreSession reopens the old session after SwapToken failed to replace it, and puts the lock back on it.
+func (b *Bot) restoreSession(ctx context.Context, d *discordgo.Session, session discord.Session) error {
+	log.Ctx(ctx).Warn().Msg("Reopening old session")
+	err := d.Open()
+	if err != nil && !errors.Is(err, discordgo.ErrWSAlreadyOpen) {
+		return fmt.Errorf("reopening old Discord session: %w", err)
+	}
+	b.restoreLock(ctx, session)
+	return nil
+}
+
+// current gets the Discord session, which SwapToken may replace at any time.
+func (b *Bot) current() (*discordgo.Session, discord.Session) {
+	b.sessionMu.RLock()
+	defer b.sessionMu.RUnlock()
+	return b.d, b.session
+}
+
+// connect adds the handlers to the Discord session and connects it, then registers the commands.
+func (b *Bot) connect(ctx context.Context, progress bots.BootProgress) error {
+	d, session := b.current()
+
 	log.Ctx(ctx).Trace().Msg("Adding handlers")
 	// TODO more handlers
-	b.d.AddHandler(b.messageCreate)
-	b.d.AddHandler(b.presenceChanged)
-	b.d.AddHandler(b.userChanged)
-	b.d.AddHandler(b.interactionHandler)
-	b.d.AddHandler(b.connectHandler)
-	b.d.AddHandler(b.disconnectHandler)
-
-	b.d.ShouldReconnectOnError = true
-	b.d.ShouldRetryOnRateLimit = true
+	d.AddHandler(discord.Handler(b.messageCreate))
+	d.AddHandler(discord.Handler(b.presenceChanged))
+	d.AddHandler(discord.Handler(b.userChanged))
+	d.AddHandler(discord.Handler(b.interactionHandler))
+	d.AddHandler(b.connectHandler)
+	d.AddHandler(b.disconnectHandler)
+	d.AddHandler(b.readyHandler)
+	d.AddHandler(b.resumedHandler)
+
+	// discordgo's reconnect loop keeps going even after the session is closed, so whatever is watching the Synth
+	// reconnects it instead
+	d.ShouldReconnectOnError = b.watcher == nil
+	d.ShouldRetryOnRateLimit = true
 
 	// TODO intents
-	b.d.Identify.Intents = discordgo.IntentsGuildMessages |
+	d.Identify.Intents = discordgo.IntentsGuildMessages |
 		discordgo.IntentsGuildPresences |
 		discordgo.IntentsGuildMembers |
 		discordgo.IntentsGuildMessageReactions |
 		discordgo.IntentsDirectMessages
 
 	log.Ctx(ctx).Trace().Msg("Connecting synth")
-	err = b.d.Open()
+	err := d.Open()
 	if err != nil {
 		return fmt.Errorf("opening Discord session: %w", err)
 	}
+	progress.Report(bots.BootConnected)
 
 	ctx = b.loggerCtx(ctx)
-	err = b.cmdGroup.Register(ctx, b.d)
+	err = b.cmdGroup.Register(ctx, session)
 	if err != nil {
 		return fmt.Errorf("registering commands: %w", err)
 	}
+	progress.Report(bots.BootCommandsRegistered)
+
+	b.restoreLock(ctx, session)
+	progress.Report(bots.BootReady)
 
 	return nil
 }
 
-func (b *Bot) interactionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (b *Bot) interactionHandler(s discord.Session, i *discordgo.InteractionCreate) {
 	switch i.Type {
-	case discordgo.InteractionApplicationCommand:
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete,
+		discordgo.InteractionMessageComponent, discordgo.InteractionModalSubmit:
 		b.cmdGroup.Handler(s, i)
-	case discordgo.InteractionMessageComponent, discordgo.InteractionModalSubmit:
-		b.configInteractionHandler(s, i)
 	default:
 		b.trace(b.loggerCtx(context.Background())).
 			Str("type", i.Type.String()).
@@ -100,26 +246,49 @@ func (b *Bot) interactionHandler(s *discordgo.Session, i *discordgo.InteractionC
 
 // loggerCtx attaches information about this Synth to a logger in the context.Context.
 func (b *Bot) loggerCtx(ctx context.Context) context.Context {
-	return log.Ctx(ctx).With().
-		Str("user_id", b.synth.DiscordUserID).
-		Str("bot_username", b.d.State.User.Username).
-		Logger().WithContext(ctx)
+	logger := log.Ctx(ctx).With().Str("user_id", b.synth.DiscordUserID)
+	// the bot's user isn't known until it has connected, which it might never have
+	b.sessionMu.RLock()
+	session, appID := b.session, b.synth.ApplicationID
+	b.sessionMu.RUnlock()
+	var me *discordgo.User
+	if session != nil {
+		me = session.Me()
+	}
+	if me != nil {
+		logger = logger.Str("bot_username", me.Username)
+	} else {
+		logger = logger.Str("application_id", appID)
+	}
+	return logger.Logger().WithContext(ctx)
 }
 
 // setup configures the bare essentials for the Discord client, but does not connect it.
 func (b *Bot) setup(ctx context.Context) error {
 	log.Ctx(ctx).Trace().Msg("Setting up synth")
 
+	b.sessionMu.Lock()
+	defer b.sessionMu.Unlock()
 	if b.d != nil {
 		return errors.New("bot already setup")
 	}
 
-	s, err := discordgo.New("Bot " + b.synth.Token)
+	s, err := b.newSession(ctx, b.synth.Token)
 	if err != nil {
-		return fmt.Errorf("creating Discord session: %w", err)
+		return err
 	}
 
 	b.d = s
+	b.session = discord.Wrap(s)
+	return nil
+}
+
+// newSession creates a Discord session for a token, but does not connect it.
+func (b *Bot) newSession(ctx context.Context, token string) (*discordgo.Session, error) {
+	s, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, fmt.Errorf("creating Discord session: %w", err)
+	}
 
 	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
 		log.Ctx(ctx).Info().
@@ -127,18 +296,49 @@ func (b *Bot) setup(ctx context.Context) error {
 			Msg("Synth logged in")
 	})
 
-	return nil
+	return s, nil
 }
 
+// Close disconnects the Synth from Discord. It is safe to call even if the Synth failed to start.
 func (b *Bot) Close() error {
-	return b.d.Close()
+	d, _ := b.current()
+	if d == nil {
+		return nil
+	}
+	b.lockMu.Lock()
+	if b.lockTimer != nil {
+		b.lockTimer.Stop()
+	}
+	b.lockMu.Unlock()
+	return d.Close()
+}
+
+// Delete removes the Synth's commands from Discord and closes it, for when the Synth is being deleted. The bot is
+// still closed if its commands can't be removed.
+func (b *Bot) Delete(ctx context.Context) error {
+	d, session := b.current()
+	if d == nil || b.cmdGroup == nil || session.Me() == nil {
+		// it never connected, so its commands can't be removed, and it may never have registered any
+		return b.Close()
+	}
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("Deleting synth")
+
+	err := b.cmdGroup.Unregister(ctx, session)
+	if err != nil {
+		err = fmt.Errorf("unregistering commands: %w", err)
+	}
+	return errors.Join(err, b.Close())
 }
 
-func (b *Bot) userChanged(s *discordgo.Session, u *discordgo.UserUpdate) {
+func (b *Bot) userChanged(s discord.Session, u *discordgo.UserUpdate) {
 	fmt.Printf("%+v\n", *u)
 }
 
-func (b *Bot) presenceChanged(s *discordgo.Session, p *discordgo.PresenceUpdate) {
+func (b *Bot) presenceChanged(s discord.Session, p *discordgo.PresenceUpdate) {
+	ctx := b.loggerCtx(context.Background())
+	defer bots.Recover(ctx)
+
 	// we only care about our synth user
 	if p.User.ID != b.synth.DiscordUserID {
 		return
@@ -150,20 +350,24 @@ func (b *Bot) presenceChanged(s *discordgo.Session, p *discordgo.PresenceUpdate)
 		st = discordgo.StatusInvisible
 	}
 
-	err := s.UpdateStatusComplex(discordgo.UpdateStatusData{
+	b.lockMu.Lock()
+	b.presence = discordgo.UpdateStatusData{
 		IdleSince:  p.Presence.Since,
 		Activities: p.Activities,
 		AFK:        false,
 		Status:     string(st),
-	})
-	// FIXME this shouldn't be here lol
+	}
+	b.lockMu.Unlock()
+
+	err := b.updatePresence(s)
 	if err != nil {
-		panic(err)
+		log.Ctx(ctx).Err(err).Msg("Error updating presence")
 	}
 }
 
-func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (b *Bot) messageCreate(s discord.Session, m *discordgo.MessageCreate) {
 	ctx := b.loggerCtx(context.Background())
+	defer bots.Recover(ctx)
 	b.trace(ctx).
 		Str("m.Author.Username", m.Author.Username).
 		Str("m.ChannelID", m.ChannelID).
@@ -171,7 +375,7 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 
 	// Ignore all messages created by the bot itself
 	// This isn't required in this specific example but it's a good practice.
-	if m.Author.ID == s.State.User.ID {
+	if m.Author.ID == s.Me().ID {
 		return
 	}
 
@@ -183,10 +387,14 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	sendNewMessage := true
 	deleteOldMessage := true
 	var ref *discordgo.MessageReference
+	content := m.Content
+	// messages don't say what language their author uses, unlike interactions
+	locale := i18n.DefaultLocale
 
 	channel, err := s.Channel(m.ChannelID)
 	if err != nil {
-		panic(err)
+		log.Ctx(ctx).Err(err).Msg("Error getting channel")
+		return
 	}
 	if channel.Type == discordgo.ChannelTypeDM {
 		// we can't delete messages in DMs
@@ -194,6 +402,7 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	}
 
 	// if this is a ref to a message in this channel
+	var editID string
 	if m.MessageReference != nil &&
 		m.MessageReference.Type == discordgo.MessageReferenceTypeDefault &&
 		m.MessageReference.GuildID == m.GuildID &&
@@ -210,23 +419,74 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		}
 
 		// if the ref is a message we sent
-		if reply.Author.ID == s.State.User.ID && strings.HasPrefix(m.Content, commandEdit) {
-			_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
-				Channel: m.ChannelID,
-				ID:      reply.ID,
-				Content: new(m.Content[len(commandEdit):]),
-				AllowedMentions: &discordgo.MessageAllowedMentions{
-					Parse: []discordgo.AllowedMentionType{
-						discordgo.AllowedMentionTypeUsers,
-						discordgo.AllowedMentionTypeRoles,
-					},
-				},
-			})
+		if reply.Author.ID == s.Me().ID && strings.HasPrefix(m.Content, commandEdit) {
+			editID = reply.ID
+			content = m.Content[len(commandEdit):]
+			sendNewMessage = false
+		}
+	}
+
+	content, gs, cost, err := b.compose(ctx, s, m.GuildID, content, m.Message, sendNewMessage)
+	var refused *refusedError
+	if errors.Is(err, errMuted) {
+		// the owner knows their Synth is muted, so the message just disappears
+		if deleteOldMessage {
+			err = s.ChannelMessageDelete(m.ChannelID, m.ID)
 			if err != nil {
-				log.Ctx(ctx).Err(err).Msg("Error editing message")
+				log.Ctx(ctx).Err(err).Msg("Error deleting message")
+			}
+		}
+		return
+	} else if errors.As(err, &refused) {
+		b.refuse(ctx, s, m, locale, deleteOldMessage, refused.message(locale))
+		return
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error composing message")
+		_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(locale, "proxy.failed"))
+		return
+	}
+
+	if editID != "" {
+		_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Channel: m.ChannelID,
+			ID:      editID,
+			Content: new(content),
+			AllowedMentions: &discordgo.MessageAllowedMentions{
+				Parse: []discordgo.AllowedMentionType{
+					discordgo.AllowedMentionTypeUsers,
+					discordgo.AllowedMentionTypeRoles,
+				},
+			},
+		})
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("Error editing message")
+			return
+		}
+	}
+
+	if sendNewMessage {
+		// see if any are too large before we charge for them or download them
+		for _, attach := range m.Attachments {
+			if attach.Size > maxProxyFileSize {
+				_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(locale, "proxy.file_too_large", maxProxyFileSize/1024))
 				return
 			}
-			sendNewMessage = false
+		}
+	}
+
+	// the energy is spent before sending, so that messages sent at the same time can't overspend it, and refunded if
+	// the message couldn't be sent
+	var spent float64
+	if sendNewMessage && gs != nil {
+		ok, msg, err := b.spendEnergy(ctx, locale, gs, cost)
+		if err != nil {
+			// don't silence the Synth because of our own problems
+			log.Ctx(ctx).Err(err).Msg("Error spending energy")
+		} else if !ok {
+			b.refuse(ctx, s, m, locale, deleteOldMessage, msg)
+			return
+		} else {
+			spent = cost
 		}
 	}
 
@@ -250,18 +510,12 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		}
 
 		var files []*discordgo.File
-		// see if any are too large before we download them
-		for _, attach := range m.Attachments {
-			if attach.Size > maxProxyFileSize {
-				_, _ = s.ChannelMessageSend(m.ChannelID, "File too large to proxy (max "+fmt.Sprint(maxProxyFileSize/1024)+" KB)")
-				return
-			}
-		}
 		for _, attach := range m.Attachments {
 			body, err := s.RequestWithBucketID("GET", attach.URL, nil, "TODO") // TODO rate limit bucket
 			if err != nil {
 				log.Ctx(ctx).Err(err).Msg("Error downloading attachment")
-				_, _ = s.ChannelMessageSend(m.ChannelID, "Unable to download attachment!")
+				b.refundEnergy(ctx, gs, spent)
+				_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(locale, "proxy.download_failed"))
 				return
 			}
 			files = append(files, &discordgo.File{
@@ -272,7 +526,7 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		}
 
 		_, err := s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-			Content:    m.Content,
+			Content:    content,
 			Reference:  ref,
 			Flags:      flags,
 			StickerIDs: stickerIDs,
@@ -287,7 +541,8 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		})
 		if err != nil {
 			log.Ctx(ctx).Err(err).Msg("Error sending message")
-			_, _ = s.ChannelMessageSend(m.ChannelID, "Unable to proxy message!")
+			b.refundEnergy(ctx, gs, spent)
+			_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(locale, "proxy.failed"))
 			return
 		}
 	}
@@ -301,7 +556,82 @@ func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	}
 }
 
-func (b *Bot) deferredEphemeralMessage(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+// refusedError is returned by compose when the Synth is not allowed to say something.
+type refusedError struct {
+	// key is the message explaining why, and args are its arguments.
+	key  string
+	args []any
+}
+
+func (e *refusedError) Error() string {
+	return "refused: " + e.message(i18n.DefaultLocale)
+}
+
+// message explains the refusal to the owner.
+func (e *refusedError) message(locale discordgo.Locale) string {
+	return i18n.T(locale, e.key, e.args...)
+}
+
+// compose prepares content for the Synth to say: the lock is applied everywhere, and then the speech rules and
+// template of the guild, if any. m supplies the attachments and stickers that will be sent with the content. If charge
+// is set, the energy cost is calculated so it can be spent once the message is ready to be sent. The GuildSettings are
+// nil outside of guilds, as settings are per guild and DMs are unrestricted.
+//
+// errMuted is returned if the Synth may not say anything, and a *refusedError if it may not say this.
+func (b *Bot) compose(ctx context.Context, s discord.Session, guildID, content string, m *discordgo.Message, charge bool) (string, *database.GuildSettings, float64, error) {
+	content, err := b.applyLock(ctx, content, m)
+	var rejected *speech.RejectedError
+	if errors.As(err, &rejected) {
+		return "", nil, 0, &refusedError{key: "proxy.refused.locked", args: []any{rejected.Reason}}
+	} else if err != nil {
+		return "", nil, 0, err
+	}
+
+	if guildID == "" {
+		return content, nil, 0, nil
+	}
+
+	gs, err := b.synth.GuildSettings(ctx, guildID)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("getting guild settings: %w", err)
+	}
+
+	sm, err := b.applySpeechRules(ctx, gs, content)
+	if errors.As(err, &rejected) {
+		return "", nil, 0, &refusedError{key: "proxy.refused.rules", args: []any{rejected.Reason}}
+	} else if err != nil {
+		return "", nil, 0, fmt.Errorf("applying speech rules: %w", err)
+	}
+
+	// the template is free, so only what the Synth actually said costs energy
+	var cost float64
+	if charge {
+		cost = energyCost(sm.Content, m)
+	}
+	content, err = b.renderContent(ctx, s, gs, sm, cost)
+	if errors.Is(err, speech.ErrTooLong) {
+		return "", nil, 0, &refusedError{key: "proxy.refused.too_long", args: []any{err}}
+	} else if err != nil {
+		return "", nil, 0, fmt.Errorf("rendering message: %w", err)
+	}
+	return content, gs, cost, nil
+}
+
+// refuse handles a message that the Synth is not allowed to proxy, by removing it and telling the owner why.
+func (b *Bot) refuse(ctx context.Context, s discord.Session, m *discordgo.MessageCreate, locale discordgo.Locale, deleteOldMessage bool, reason string) {
+	if deleteOldMessage {
+		err := s.ChannelMessageDelete(m.ChannelID, m.ID)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("Error deleting message")
+		}
+	}
+	err := b.notifyOwner(s, i18n.T(locale, "proxy.refused.notice", reason, m.Content))
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error notifying owner")
+	}
+}
+
+func (b *Bot) deferredEphemeralMessage(s discord.Session, i *discordgo.InteractionCreate) error {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -323,10 +653,37 @@ func (b *Bot) trace(ctx context.Context) *zerolog.Event {
 
 func (b *Bot) connectHandler(_ *discordgo.Session, _ *discordgo.Connect) {
 	ctx := b.loggerCtx(context.Background())
+	defer bots.Recover(ctx)
 	log.Ctx(ctx).Warn().Msg("Connected.")
 }
 
-func (b *Bot) disconnectHandler(_ *discordgo.Session, _ *discordgo.Disconnect) {
+func (b *Bot) disconnectHandler(s *discordgo.Session, _ *discordgo.Disconnect) {
 	ctx := b.loggerCtx(context.Background())
+	defer bots.Recover(ctx)
 	log.Ctx(ctx).Warn().Msg("Disconnected.")
+	b.report(s, bots.SynthDegraded)
+}
+
+func (b *Bot) readyHandler(s *discordgo.Session, _ *discordgo.Ready) {
+	defer bots.Recover(b.loggerCtx(context.Background()))
+	b.report(s, bots.SynthReady)
+}
+
+func (b *Bot) resumedHandler(s *discordgo.Session, _ *discordgo.Resumed) {
+	ctx := b.loggerCtx(context.Background())
+	defer bots.Recover(ctx)
+	log.Ctx(ctx).Info().Msg("Resumed.")
+	b.report(s, bots.SynthReady)
+}
+
+// report tells the watcher about a change in the connection to Discord. Changes to a session that SwapToken is
+// replacing, or has replaced, aren't reported.
+func (b *Bot) report(s *discordgo.Session, state bots.SynthState) {
+	if b.watcher == nil || b.swapping.Load() {
+		return
+	}
+	if d, _ := b.current(); d != s {
+		return
+	}
+	b.watcher(state)
 }
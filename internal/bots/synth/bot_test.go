@@ -0,0 +1,273 @@
+package synth
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/bots"
+	"github.com/ajanata/synthos/internal/config"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+)
+
+// newTestBot makes a Synth for owner, backed by a fresh database and a fake Discord.
+func newTestBot(t *testing.T, owner *discordgo.User) (*Bot, *discordtest.Session) {
+	t.Helper()
+	db, err := database.New(config.Database{
+		DBDriver: config.Sqlite3DBDriver,
+		DSN:      filepath.Join(t.TempDir(), "synthos.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.InsertSynth(t.Context(), owner.ID, "app-id", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	synth, err := db.GetSynth(t.Context(), owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(config.SynthOS{}, synth)
+	s := discordtest.New("synth")
+	b.session = s
+	return b, s
+}
+
+func TestMessageCreateProxies(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	channel := s.AddChannel("guild", discordgo.ChannelTypeGuildText)
+
+	m := s.Post(channel.ID, owner, "hello")
+	b.messageCreate(s, m)
+
+	sent := s.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if sent[0].Content != "hello" || sent[0].ChannelID != channel.ID {
+		t.Errorf("sent %q to %s, want %q to %s", sent[0].Content, sent[0].ChannelID, "hello", channel.ID)
+	}
+	if !slices.Equal(s.Deleted(), []string{m.ID}) {
+		t.Errorf("deleted %v, want the original message", s.Deleted())
+	}
+}
+
+func TestMessageCreateIgnoresOthers(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	channel := s.AddChannel("guild", discordgo.ChannelTypeGuildText)
+
+	b.messageCreate(s, s.Post(channel.ID, discordtest.NewUser("other"), "hello"))
+	b.messageCreate(s, s.Post(channel.ID, s.Me(), "hello"))
+
+	if len(s.Sent()) != 0 || len(s.Deleted()) != 0 {
+		t.Errorf("sent %d and deleted %d messages that were not the owner's", len(s.Sent()), len(s.Deleted()))
+	}
+}
+
+func TestMessageCreateInDM(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	dm := s.AddChannel("", discordgo.ChannelTypeDM)
+
+	b.messageCreate(s, s.Post(dm.ID, owner, "hello"))
+
+	if len(s.Sent()) != 1 {
+		t.Errorf("sent %d messages, want 1", len(s.Sent()))
+	}
+	if len(s.Deleted()) != 0 {
+		t.Error("deleted a message in a DM")
+	}
+}
+
+func TestMessageCreateEdits(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	channel := s.AddChannel("guild", discordgo.ChannelTypeGuildText)
+
+	b.messageCreate(s, s.Post(channel.ID, owner, "helo"))
+	orig := s.Sent()[0]
+
+	m := s.Post(channel.ID, owner, commandEdit+"hello")
+	m.MessageReference = &discordgo.MessageReference{
+		Type:      discordgo.MessageReferenceTypeDefault,
+		MessageID: orig.ID,
+		ChannelID: channel.ID,
+		GuildID:   "guild",
+	}
+	b.messageCreate(s, m)
+
+	if n := len(s.Sent()); n != 1 {
+		t.Errorf("sent %d messages, want only the original", n)
+	}
+	if got := s.Message(orig.ID).Content; got != "hello" {
+		t.Errorf("edited message is %q, want %q", got, "hello")
+	}
+	if s.Message(m.ID) != nil {
+		t.Error("the edit command was not deleted")
+	}
+}
+
+func TestMessageCreateAttachments(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	channel := s.AddChannel("guild", discordgo.ChannelTypeGuildText)
+
+	const url = "https://cdn.example.com/drone.png"
+	s.AddFile(url, []byte("png"))
+	m := s.Post(channel.ID, owner, "look")
+	m.Attachments = []*discordgo.MessageAttachment{{
+		URL:         url,
+		Filename:    "drone.png",
+		ContentType: "image/png",
+		Size:        3,
+	}}
+	b.messageCreate(s, m)
+
+	sent := s.Sent()
+	if len(sent) != 1 || len(sent[0].Attachments) != 1 || sent[0].Attachments[0].Filename != "drone.png" {
+		t.Fatalf("sent %+v, want the attachment", sent)
+	}
+}
+
+func TestMessageCreateRefundsEnergy(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	channel := s.AddChannel("guild", discordgo.ChannelTypeGuildText)
+	gs, err := b.synth.GuildSettings(t.Context(), "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs.MaxEnergy = 100
+	err = gs.Save(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := b.synth.Energy(t.Context(), "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Current = 100
+	err = e.Save(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Fail("ChannelMessageSendComplex", errors.New("discord is down"))
+	b.messageCreate(s, s.Post(channel.ID, owner, "hello"))
+
+	e, err = b.synth.Energy(t.Context(), "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Current != 100 {
+		t.Errorf("energy is %v, want it refunded to 100", e.Current)
+	}
+}
+
+func TestMessageCreateMuted(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	channel := s.AddChannel("guild", discordgo.ChannelTypeGuildText)
+	err := b.synth.Lock(t.Context(), database.LockMute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.Post(channel.ID, owner, "hello")
+	b.messageCreate(s, m)
+
+	if len(s.Sent()) != 0 {
+		t.Error("a muted Synth spoke")
+	}
+	if !slices.Equal(s.Deleted(), []string{m.ID}) {
+		t.Errorf("deleted %v, want the original message", s.Deleted())
+	}
+}
+
+func TestCloseBeforeConnecting(t *testing.T) {
+	b, _ := newTestBot(t, discordtest.NewUser("owner"))
+	disconnected := make(chan bots.SynthState, 1)
+	b.Watch(func(state bots.SynthState) {
+		disconnected <- state
+	})
+
+	// like a Synth whose token stopped working, it has a session that never opened
+	err := b.setup(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.d.AddHandler(b.disconnectHandler)
+
+	err = b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// discordgo reports the disconnect from another goroutine, which would take down the process if it panicked
+	select {
+	case state := <-disconnected:
+		if state != bots.SynthDegraded {
+			t.Errorf("reported %s, want degraded", state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the disconnect was not reported")
+	}
+}
+
+func TestDeleteBeforeConnecting(t *testing.T) {
+	b, _ := newTestBot(t, discordtest.NewUser("owner"))
+	// like a Synth that failed to connect, it has its commands but never learned who it is
+	err := b.setup(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.buildCommands(t.Context())
+
+	err = b.Delete(t.Context())
+	if err != nil {
+		t.Errorf("Delete returned %v", err)
+	}
+}
+
+func TestReplacedSessionNotReported(t *testing.T) {
+	b, _ := newTestBot(t, discordtest.NewUser("owner"))
+	reported := make(chan bots.SynthState, 2)
+	b.Watch(func(state bots.SynthState) {
+		reported <- state
+	})
+
+	err := b.setup(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := b.d
+	old.AddHandler(b.disconnectHandler)
+	// like SwapToken, replace the session with one for another token
+	d, err := b.newSession(t.Context(), "new-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.AddHandler(b.disconnectHandler)
+	b.d = d
+
+	_ = old.Close()
+	_ = d.Close()
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the new session's disconnect was not reported")
+	}
+	select {
+	case <-reported:
+		t.Error("the old session's disconnect was reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -5,68 +5,141 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
 
 	"github.com/ajanata/synthos/internal/authorizer"
 	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
 )
 
+// Reasons that policies allow commands.
+const (
+	reasonOwner     = "owner"
+	reasonAdmin     = "admin"
+	reasonHandler   = "handler"
+	reasonModerator = "moderator"
+)
+
+// Discord only allows a few avatar changes in a short time, and the configuration menu makes several requests to
+// show.
+const (
+	avatarCooldown    = 5 * time.Minute
+	configureCooldown = 5 * time.Second
+)
+
+// avatarCooldownKey is shared by everything that changes the avatar, so that Discord's limit applies to all of them.
+const avatarCooldownKey = "avatar"
+
+// auditActions are the audit log actions recorded when someone other than the owner is allowed to use a command.
+var auditActions = map[string]database.AuditAction{
+	reasonAdmin:     database.AuditAdminCommand,
+	reasonHandler:   database.AuditHandlerCommand,
+	reasonModerator: database.AuditModeratorCommand,
+}
+
 func (b *Bot) buildCommands(ctx context.Context) {
 	log.Ctx(ctx).Trace().Msg("Building commands")
 
-	b.cmdGroup = command.NewGroup()
+	b.cmdGroup = command.NewGroup().ForOwner(b.synth.DiscordUserID).InGuild(b.commandGuildID).Use(command.Timing())
+
+	b.ownerOnly = authorizer.Chain{
+		authorizer.AllowIf(reasonOwner, authorizer.Self{}),
+	}
+	b.controllers = audited{
+		synth: b.synth,
+		Policy: authorizer.Chain{
+			authorizer.AllowIf(reasonOwner, authorizer.Self{}),
+			authorizer.AllowIf(reasonAdmin, authorizer.Admin{ID: b.adminID}),
+			authorizer.AllowIf(reasonHandler, authorizer.Delegated{Handlers: b.synth}),
+			authorizer.AllowIf(reasonModerator, authorizer.GuildRole{
+				Permissions: discordgo.PermissionManageGuild,
+				Roles:       b.synth,
+			}),
+		},
+	}
+	b.handlers = audited{
+		synth: b.synth,
+		Policy: authorizer.Chain{
+			authorizer.AllowIf(reasonOwner, authorizer.Self{}),
+			authorizer.AllowIf(reasonAdmin, authorizer.Admin{ID: b.adminID}),
+			authorizer.AllowIf(reasonHandler, authorizer.Delegated{Handlers: b.synth}),
+		},
+	}
+	b.moderators = audited{
+		synth: b.synth,
+		Policy: authorizer.Chain{
+			authorizer.AllowIf(reasonOwner, authorizer.Self{}),
+			authorizer.AllowIf(reasonAdmin, authorizer.Admin{ID: b.adminID}),
+			authorizer.AllowIf(reasonHandler, authorizer.Delegated{Handlers: b.synth}),
+			// without the handler role, so that it can't be used to hand itself to someone else
+			authorizer.AllowIf(reasonModerator, authorizer.GuildRole{Permissions: discordgo.PermissionManageGuild}),
+		},
+	}
 
 	b.cmdGroup.Command("update-avatar").
-		Description("Sync your global avatar to your Synth instance.").
+		Localize(i18n.Default(), "commands.update-avatar").
 		Handler(b.updateAvatar).
+		// the avatar is copied from the user running the command, so only the owner can use it
+		Policy(b.ownerOnly).
+		SharedCooldown(avatarCooldownKey, avatarCooldown, command.PerGroup).
 		InteractionContext(discordgo.InteractionContextBotDM).
 		Build()
 
 	b.cmdGroup.Command("configure").
-		Description("Configure options for this Synth instance on this server.").
+		Localize(i18n.Default(), "commands.configure").
 		Handler(b.configure).
+		Policy(b.controllers).
+		Cooldown(configureCooldown, command.PerUser).
 		InteractionContext(discordgo.InteractionContextGuild).
 		Build()
+
+	b.buildRulesCommands()
+	b.buildLockCommands()
+	b.buildPhraseCommands()
+	b.buildContextMenuCommands()
+	b.buildConfigRoutes()
 }
 
-func (b *Bot) authorized(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) (bool, error) {
-	// TODO support more than just self authorized
-	auth := authorizer.Self{}
-	authorized, err := auth.Authorized(ctx, b.synth.DiscordUserID, u)
-	if err != nil {
-		_ = b.InteractionSimpleTextResponse(s, i.Interaction, "Failed to authorize. SynthOS Controller has been notified.")
-		return false, err
+// audited wraps a Policy to keep a record of everything that anyone other than the owner is allowed to do.
+type audited struct {
+	authorizer.Policy
+	synth *database.Synth
+}
+
+func (a audited) Decide(ctx context.Context, r authorizer.Request) (authorizer.Decision, error) {
+	d, err := a.Policy.Decide(ctx, r)
+	// suggestions are requested on every keystroke, and don't do anything
+	if err != nil || d.Effect != authorizer.Allow || r.Autocomplete {
+		return d, err
 	}
-	if !authorized {
-		return false, b.InteractionSimpleTextResponse(s, i.Interaction, "You are not authorized to use this command.")
+
+	if action, ok := auditActions[d.Reason]; ok {
+		err = a.synth.Audit(ctx, &database.AuditEntry{
+			ActorID: r.User.ID,
+			Action:  action,
+			GuildID: r.GuildID,
+			Detail:  r.Command,
+		})
+		if err != nil {
+			return authorizer.Decision{Effect: authorizer.Deny}, fmt.Errorf("recording audit entry: %w", err)
+		}
 	}
-	return authorized, nil
+	return d, nil
 }
 
-func (b *Bot) updateAvatar(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+func (b *Bot) updateAvatar(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
 	ctx = b.loggerCtx(ctx)
 	log.Ctx(ctx).Info().Msg("update avatar handler")
 
-	if auth, err := b.authorized(ctx, s, u, i); err != nil || !auth {
-		return err
-	}
-
-	auth := authorizer.Self{}
-	authorized, err := auth.Authorized(ctx, b.synth.DiscordUserID, u)
-	if err != nil {
-		_ = b.InteractionSimpleTextResponse(s, i.Interaction, "Failed to authorize. SynthOS Controller has been notified.")
-		return err
-	}
-	if !authorized {
-		return b.InteractionSimpleTextResponse(s, i.Interaction, "You are not authorized to use this command.")
-	}
-
 	// code lifted from discordgo as we want the raw bytes, not an image.Image
 	body, err := s.RequestWithBucketID("GET", discordgo.EndpointUserAvatar(u.ID, u.Avatar), nil, discordgo.EndpointUserAvatar("", ""))
 	if err != nil {
-		_ = b.InteractionSimpleTextResponse(s, i.Interaction, "Failed to download avatar. SynthOS Controller has been notified.")
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "configure.avatar.download_failed"))
 		return err
 	}
 
@@ -74,9 +147,9 @@ func (b *Bot) updateAvatar(ctx context.Context, s *discordgo.Session, u *discord
 	b64 := base64.StdEncoding.EncodeToString(body)
 	_, err = s.UserUpdate("", fmt.Sprintf("data:%s;base64,%s", contentType, b64), "")
 	if err != nil {
-		_ = b.InteractionSimpleTextResponse(s, i.Interaction, "Failed to update avatar. SynthOS Controller has been notified.")
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "configure.avatar.failed"))
 		return err
 	}
 
-	return b.InteractionSimpleTextResponse(s, i.Interaction, "Avatar updated successfully.")
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "configure.avatar.updated"))
 }
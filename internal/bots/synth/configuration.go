@@ -4,74 +4,205 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+	"github.com/ajanata/synthos/internal/speech"
 )
 
 const (
 	maxAvatarSize = 10 * 1024 * 1024
 )
 
-func numericButtonsRow(idPrefix string, curValue int) discordgo.ActionsRow {
+// Prefixes of the custom IDs of the configuration menu's components, and the modals they open.
+const (
+	configEnergy        = "energy"
+	configPronounToggle = "pronoun_toggle"
+	configPronouns      = "pronouns"
+	configTemplate      = "template"
+	configHandlerRole   = "handler_role"
+	configAllowLogging  = "allow_logging"
+	configSynthName     = "synth_name"
+	configAvatar        = "avatar"
+	configBio           = "bio"
+)
+
+// The energy settings, as used in the custom IDs of their buttons.
+const (
+	energyMax   = "max"
+	energyRegen = "regen"
+)
+
+// buildConfigRoutes registers the handlers for the configuration menu. Most use the same policy as /configure, as anyone
+// that can use the menu can change this guild's configuration. The settings that apply in every guild, and who else
+// controls the Synth in this guild, are limited to the people trusted with them.
+func (b *Bot) buildConfigRoutes() {
+	b.cmdGroup.Component(configEnergy).Handler(b.configUpdate(b.energyButton)).Policy(b.controllers).Build()
+	b.cmdGroup.Component(configPronounToggle).Handler(b.configUpdate(b.togglePronouns)).Policy(b.controllers).Build()
+	b.cmdGroup.Component(configHandlerRole).Handler(b.configUpdate(b.selectHandlerRole)).Policy(b.moderators).Build()
+	b.cmdGroup.Component(configAllowLogging).Handler(b.configUpdate(b.selectAllowLogging)).Policy(b.ownerOnly).Build()
+	b.cmdGroup.Component(configPronouns).Handler(b.pronounsModal).Policy(b.controllers).Build()
+	b.cmdGroup.Component(configTemplate).Handler(b.templateModal).Policy(b.controllers).Build()
+	b.cmdGroup.Component(configSynthName).Handler(b.synthNameModal).Policy(b.controllers).Build()
+	b.cmdGroup.Component(configAvatar).Handler(b.avatarModal).Policy(b.handlers).Build()
+	b.cmdGroup.Component(configBio).Handler(b.bioModal).Policy(b.controllers).Build()
+
+	b.cmdGroup.Modal(configPronouns).Handler(b.configSubmit(b.submitPronouns)).Policy(b.controllers).Build()
+	b.cmdGroup.Modal(configTemplate).Handler(b.configSubmit(b.submitTemplate)).Policy(b.controllers).Build()
+	b.cmdGroup.Modal(configSynthName).Handler(b.configSubmit(b.submitSynthName)).Policy(b.controllers).
+		Cooldown(configureCooldown, command.PerGuild).Build()
+	b.cmdGroup.Modal(configAvatar).Handler(b.configSubmit(b.submitAvatar)).Policy(b.handlers).
+		SharedCooldown(avatarCooldownKey, avatarCooldown, command.PerGroup).Build()
+	b.cmdGroup.Modal(configBio).Handler(b.configSubmit(b.submitBio)).Policy(b.controllers).Build()
+}
+
+// numericButtonsRow shows the current value of an energy setting, with buttons to change it.
+func numericButtonsRow(setting string, curValue int) discordgo.ActionsRow {
 	return discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			discordgo.Button{
 				Label:    "-10",
 				Style:    discordgo.SecondaryButton,
-				CustomID: idPrefix + "_m10",
+				CustomID: command.NewCustomID(configEnergy, setting, -10).String(),
 			},
 			discordgo.Button{
 				Label:    "-1",
 				Style:    discordgo.PrimaryButton,
-				CustomID: idPrefix + "_m1",
+				CustomID: command.NewCustomID(configEnergy, setting, -1).String(),
 			},
 			discordgo.Button{
 				Label:    strconv.Itoa(curValue),
 				Style:    discordgo.SuccessButton,
 				Disabled: true,
-				CustomID: idPrefix + "_disp",
+				CustomID: command.NewCustomID(configEnergy, setting).String(),
 			},
 			discordgo.Button{
 				Label:    "+1",
 				Style:    discordgo.PrimaryButton,
-				CustomID: idPrefix + "_p1",
+				CustomID: command.NewCustomID(configEnergy, setting, 1).String(),
 			},
 			discordgo.Button{
 				Label:    "+10",
 				Style:    discordgo.SecondaryButton,
-				CustomID: idPrefix + "_p10",
+				CustomID: command.NewCustomID(configEnergy, setting, 10).String(),
 			},
 		},
 	}
 }
 
-func (b *Bot) configMenu(currentName, header string) *discordgo.InteractionResponse {
+func pronounContainer(locale discordgo.Locale, gs *database.GuildSettings) discordgo.Container {
+	status, toggle := i18n.T(locale, "configure.pronouns.off"), i18n.T(locale, "configure.pronouns.turn_on")
+	if gs.PronounRewrite {
+		status, toggle = i18n.T(locale, "configure.pronouns.on"), i18n.T(locale, "configure.pronouns.turn_off")
+	}
+	return discordgo.Container{
+		Components: []discordgo.MessageComponent{
+			discordgo.TextDisplay{
+				Content: i18n.T(locale, "configure.pronouns.status", status),
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    toggle,
+						Style:    discordgo.PrimaryButton,
+						CustomID: configPronounToggle,
+					},
+					discordgo.Button{
+						Label:    i18n.T(locale, "configure.pronouns.edit"),
+						Style:    discordgo.SecondaryButton,
+						CustomID: configPronouns,
+					},
+				},
+			},
+		},
+	}
+}
+
+func templateSection(locale discordgo.Locale, gs *database.GuildSettings) discordgo.Section {
+	content := i18n.T(locale, "configure.template.none")
+	if gs.Template != "" {
+		content = i18n.T(locale, "configure.template.current", gs.Template)
+	}
+	return discordgo.Section{
+		Components: []discordgo.MessageComponent{
+			discordgo.TextDisplay{Content: content},
+		},
+		Accessory: discordgo.Button{
+			Label:    i18n.T(locale, "configure.change"),
+			Style:    discordgo.PrimaryButton,
+			CustomID: configTemplate,
+		},
+	}
+}
+
+// templateHelp describes the placeholders that can be used in a template.
+func templateHelp(locale discordgo.Locale) string {
+	names := slices.Sorted(maps.Keys(speech.Placeholders))
+	var sb strings.Builder
+	sb.WriteString(i18n.T(locale, "configure.template.help"))
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("\n* `{%s}`: %s", name, speech.Placeholders[name]))
+	}
+	return sb.String()
+}
+
+func handlerRoleRow(locale discordgo.Locale, gs *database.GuildSettings) discordgo.ActionsRow {
+	menu := discordgo.SelectMenu{
+		MenuType:    discordgo.RoleSelectMenu,
+		CustomID:    configHandlerRole,
+		Placeholder: i18n.T(locale, "configure.handler_role.none"),
+		MinValues:   new(0),
+		MaxValues:   1,
+	}
+	if gs.HandlerRoleID != "" {
+		menu.DefaultValues = []discordgo.SelectMenuDefaultValue{
+			{
+				ID:   gs.HandlerRoleID,
+				Type: discordgo.SelectMenuDefaultValueRole,
+			},
+		}
+	}
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{menu},
+	}
+}
+
+// configMenu renders the configuration menu in the given locale, with header at the top if it is not empty.
+func (b *Bot) configMenu(locale discordgo.Locale, currentName, header string, gs *database.GuildSettings, energy *database.Energy) *discordgo.InteractionResponse {
 	menu := &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
 			Flags: discordgo.MessageFlagsEphemeral | discordgo.MessageFlagsIsComponentsV2,
 			Components: []discordgo.MessageComponent{
 				discordgo.TextDisplay{
-					Content: fmt.Sprintf("Configuration options for **%s**", currentName),
+					Content: i18n.T(locale, "configure.title", currentName),
 				},
 				discordgo.TextDisplay{
-					Content: "Allow extended debug logging? Errors are always logged.",
+					Content: i18n.T(locale, "configure.logging.prompt"),
 				},
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.SelectMenu{
-							CustomID: "allow_logging",
+							CustomID: configAllowLogging,
 							Options: []discordgo.SelectMenuOption{
 								{
-									Label:   "Allow Logging",
+									Label:   i18n.T(locale, "configure.logging.allow"),
 									Value:   "true",
 									Default: b.synth.AllowLogging,
 								},
 								{
-									Label:   "Disallow Logging",
+									Label:   i18n.T(locale, "configure.logging.disallow"),
 									Value:   "false",
 									Default: !b.synth.AllowLogging,
 								},
@@ -81,44 +212,50 @@ func (b *Bot) configMenu(currentName, header string) *discordgo.InteractionRespo
 				},
 				discordgo.Section{
 					Components: []discordgo.MessageComponent{
-						discordgo.TextDisplay{Content: fmt.Sprintf("Synth Name: **%s**", currentName)},
+						discordgo.TextDisplay{Content: i18n.T(locale, "configure.name.current", currentName)},
 					},
 					Accessory: discordgo.Button{
-						Label:    "Change",
+						Label:    i18n.T(locale, "configure.change"),
 						Style:    discordgo.PrimaryButton,
-						CustomID: "synth_name",
+						CustomID: configSynthName,
 					},
 				},
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.Button{
-							Label:    "Upload Avatar",
+							Label:    i18n.T(locale, "configure.avatar.button"),
 							Style:    discordgo.PrimaryButton,
-							CustomID: "avatar",
+							CustomID: configAvatar,
 						},
 						discordgo.Button{
-							Label:    "Change Bio",
+							Label:    i18n.T(locale, "configure.bio.button"),
 							Style:    discordgo.PrimaryButton,
-							CustomID: "bio",
+							CustomID: configBio,
 						},
 					},
 				},
 				discordgo.Container{
 					Components: []discordgo.MessageComponent{
 						discordgo.TextDisplay{
-							Content: "Maximum Energy",
+							Content: i18n.T(locale, "configure.energy.max", math.Floor(energy.Current)),
 						},
-						numericButtonsRow("max", b.maxEnergy),
+						numericButtonsRow(energyMax, gs.MaxEnergy),
 					},
 				},
 				discordgo.Container{
 					Components: []discordgo.MessageComponent{
 						discordgo.TextDisplay{
-							Content: "Energy Regen per Minute",
+							Content: i18n.T(locale, "configure.energy.regen"),
 						},
-						numericButtonsRow("regen", b.regen),
+						numericButtonsRow(energyRegen, gs.EnergyRegen),
 					},
 				},
+				pronounContainer(locale, gs),
+				templateSection(locale, gs),
+				discordgo.TextDisplay{
+					Content: i18n.T(locale, "configure.handler_role.help"),
+				},
+				handlerRoleRow(locale, gs),
 			},
 		},
 	}
@@ -130,269 +267,479 @@ func (b *Bot) configMenu(currentName, header string) *discordgo.InteractionRespo
 	return menu
 }
 
-func (b *Bot) configure(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
-	ctx = b.loggerCtx(ctx)
-	log.Ctx(ctx).Info().Msg("configure handler")
-
-	if auth, err := b.authorized(ctx, s, u, i); err != nil || !auth {
-		return err
+// currentSettings loads the settings and energy for the given guild, bringing the energy up to date for display.
+func (b *Bot) currentSettings(ctx context.Context, guildID string) (*database.GuildSettings, *database.Energy, error) {
+	gs, err := b.synth.GuildSettings(ctx, guildID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting guild settings: %w", err)
+	}
+	energy, err := b.synth.Energy(ctx, guildID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting energy: %w", err)
 	}
+	regenerate(energy, gs, time.Now())
+	return gs, energy, nil
+}
+
+// adjustEnergy changes an energy setting by the given amount.
+func (b *Bot) adjustEnergy(ctx context.Context, locale discordgo.Locale, guildID, setting string, delta int) (string, error) {
+	b.energyMu.Lock()
+	defer b.energyMu.Unlock()
 
-	m, err := s.GuildMember(i.GuildID, s.State.User.ID)
+	gs, energy, err := b.currentSettings(ctx, guildID)
 	if err != nil {
-		return fmt.Errorf("getting member: %w", err)
+		return "", err
+	}
+
+	var message string
+	switch setting {
+	case energyMax:
+		setMaxEnergy(energy, gs, gs.MaxEnergy+delta)
+		message = i18n.T(locale, "configure.energy.max_set", gs.MaxEnergy)
+	case energyRegen:
+		gs.EnergyRegen = max(gs.EnergyRegen+delta, 0)
+		message = i18n.T(locale, "configure.energy.regen_set", gs.EnergyRegen)
+	default:
+		return "", fmt.Errorf("invalid energy setting: %s", setting)
 	}
 
-	return s.InteractionRespond(i.Interaction, b.configMenu(m.Nick, ""))
+	err = gs.Save(ctx)
+	if err != nil {
+		return "", fmt.Errorf("saving guild settings: %w", err)
+	}
+	err = energy.Save(ctx)
+	if err != nil {
+		return "", fmt.Errorf("saving energy: %w", err)
+	}
+	return message, nil
 }
 
-func (b *Bot) configInteractionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	ctx := b.loggerCtx(context.Background())
+// setPronounReplacements validates and saves new pronoun replacements, returning a message for the user.
+func (b *Bot) setPronounReplacements(ctx context.Context, locale discordgo.Locale, guildID, value string) (string, error) {
+	replacements, err := speech.ParsePronounReplacements(value)
+	if err != nil {
+		return i18n.T(locale, "configure.pronouns.invalid", err), nil
+	}
+
+	gs, err := b.synth.GuildSettings(ctx, guildID)
+	if err != nil {
+		return "", fmt.Errorf("getting guild settings: %w", err)
+	}
+	gs.PronounReplacements = speech.FormatPronounReplacements(replacements)
+	err = gs.Save(ctx)
+	if err != nil {
+		return "", fmt.Errorf("saving guild settings: %w", err)
+	}
+	return i18n.T(locale, "configure.pronouns.updated"), nil
+}
 
-	var err error
-	switch i.Type {
-	case discordgo.InteractionMessageComponent:
-		err = b.configMessageComponentHandler(ctx, s, i)
-	case discordgo.InteractionModalSubmit:
-		err = b.configModalHandler(ctx, s, i)
+// setTemplate validates and saves a new message template, returning a message for the user.
+func (b *Bot) setTemplate(ctx context.Context, locale discordgo.Locale, guildID, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value != "" {
+		_, err := speech.ParseTemplate(value)
+		if err != nil {
+			return i18n.T(locale, "configure.template.invalid", err), nil
+		}
 	}
 
+	gs, err := b.synth.GuildSettings(ctx, guildID)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("Error handling interaction")
+		return "", fmt.Errorf("getting guild settings: %w", err)
 	}
+	gs.Template = value
+	err = gs.Save(ctx)
+	if err != nil {
+		return "", fmt.Errorf("saving guild settings: %w", err)
+	}
+	if value == "" {
+		return i18n.T(locale, "configure.template.removed"), nil
+	}
+	return i18n.T(locale, "configure.template.updated"), nil
 }
 
-func (b *Bot) configModalHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	b.trace(ctx).Msg("config modal handler")
+func (b *Bot) configure(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("configure handler")
 
-	data := i.ModalSubmitData()
-	modalID := strings.Split(data.CustomID, "_")
-	if len(modalID) != 2 {
-		return fmt.Errorf("invalid modal ID (has %d parts, not 2): %s", len(modalID), data.CustomID)
+	m, err := s.GuildMember(i.GuildID, s.Me().ID)
+	if err != nil {
+		return fmt.Errorf("getting member: %w", err)
 	}
 
-	err := b.deferredEphemeralMessage(s, i)
+	gs, energy, err := b.currentSettings(ctx, i.GuildID)
 	if err != nil {
 		return err
 	}
 
-	modalType := modalID[0]
+	return s.InteractionRespond(i.Interaction, b.configMenu(i.Locale, m.Nick, "", gs, energy))
+}
 
-	if len(data.Components) < 1 {
-		return fmt.Errorf("no components in modal")
-	}
+// configUpdate wraps a function that changes a setting from the configuration menu, re-rendering the menu with the
+// message it returns.
+func (b *Bot) configUpdate(f func(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, id command.CustomID) (string, error)) command.ComponentHandler {
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+		ctx = b.loggerCtx(ctx)
+		b.trace(ctx).Str("custom_id", id.Prefix).Msg("config component handler")
 
-	m, err := s.GuildMember(i.GuildID, s.State.User.ID)
-	if err != nil {
-		return fmt.Errorf("getting member: %w", err)
+		message, err := f(ctx, s, i, id)
+		if err != nil {
+			return err
+		}
+
+		m, err := s.GuildMember(i.GuildID, s.Me().ID)
+		if err != nil {
+			return fmt.Errorf("getting member: %w", err)
+		}
+		gs, energy, err := b.currentSettings(ctx, i.GuildID)
+		if err != nil {
+			return err
+		}
+
+		menu := b.configMenu(i.Locale, m.Nick, message, gs, energy)
+		menu.Type = discordgo.InteractionResponseUpdateMessage
+		return s.InteractionRespond(i.Interaction, menu)
 	}
+}
 
-	name := m.Nick
-	var message string
+// configSubmit wraps a function that changes a setting from a modal opened by the configuration menu. The function is
+// given the input of the modal's first label, and the menu is shown again with the message it returns.
+func (b *Bot) configSubmit(f func(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, input discordgo.MessageComponent) (string, error)) command.ComponentHandler {
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+		ctx = b.loggerCtx(ctx)
+		b.trace(ctx).Str("custom_id", id.Prefix).Msg("config modal handler")
 
-	switch modalType {
-	case "synthName":
-		message = "Unable to parse new name"
-		if label, ok := data.Components[0].(*discordgo.Label); ok {
-			if input, ok := label.Component.(*discordgo.TextInput); ok {
-				name = input.Value
-				err = s.GuildMemberNickname(i.GuildID, "@me", name)
-				message = "Synth name set to " + name
-				if err != nil {
-					log.Ctx(ctx).Error().Err(err).Str("new_name", name).Msg("Error setting new name")
-					message = "Unable to set new name: " + err.Error()
-				}
-			} else {
-				return fmt.Errorf("malformed interaction data")
-			}
-		} else {
-			return fmt.Errorf("malformed interaction data")
+		err := b.deferredEphemeralMessage(s, i)
+		if err != nil {
+			return err
 		}
-	case "bio":
-		message = "Unable to parse new bio"
-		if label, ok := data.Components[1].(*discordgo.Label); ok {
-			if input, ok := label.Component.(*discordgo.TextInput); ok {
-				bio := input.Value
-				_, err = s.GuildCurrentMemberEdit(i.GuildID, &discordgo.GuildCurrentMemberParams{
-					Bio: &bio,
-				})
-				message = "Synth bio updated"
-				if err != nil {
-					log.Ctx(ctx).Error().Err(err).Str("new_bio", bio).Msg("Error setting new bio")
-					message = "Unable to set new bio: " + err.Error()
-				}
-			} else {
-				return fmt.Errorf("malformed interaction data")
+
+		var input discordgo.MessageComponent
+		for _, c := range i.ModalSubmitData().Components {
+			if label, ok := c.(*discordgo.Label); ok {
+				input = label.Component
+				break
 			}
-		} else {
-			return fmt.Errorf("malformed interaction data")
 		}
-	case "avatar":
-		message = "Unable to parse new avatar"
-
-		if label, ok := data.Components[1].(*discordgo.Label); ok {
-			if avatar, ok := label.Component.(*discordgo.FileUpload); ok {
-				log.Ctx(ctx).Info().Msg("changing avatar")
-				_ = avatar
-				if len(avatar.Values) != 1 {
-					return fmt.Errorf("malformed interaction data")
-				}
-				att := data.Resolved.Attachments[avatar.Values[0]]
-				if att.Size > maxAvatarSize {
-					message = fmt.Sprintf("Avatar too large (max %d bytes, was %d bytes)", maxAvatarSize, att.Size)
-				} else if att.ContentType != "image/png" && att.ContentType != "image/jpeg" {
-					message = fmt.Sprintf("Avatar must be a PNG or JPEG (was %s)", att.ContentType)
-				} else {
-					body, err := s.RequestWithBucketID("GET", att.URL, nil, "ephemeral-attachments")
-					if err != nil {
-						return fmt.Errorf("downloading avatar: %w", err)
-					}
-					b64 := base64.StdEncoding.EncodeToString(body)
-					_, err = s.UserUpdate("", fmt.Sprintf("data:%s;base64,%s", att.ContentType, b64), "")
-					if err != nil {
-						// TODO lots of logging in this func
-						message = "Failed to update avatar. TODO SynthOS Controller has been notified."
-					}
-					message = "Avatar updated successfully."
-				}
-			} else {
-				return fmt.Errorf("malformed interaction data")
-			}
-		} else {
-			return fmt.Errorf("malformed interaction data")
+		if input == nil {
+			return fmt.Errorf("malformed interaction data: no input in modal %s", id.Prefix)
 		}
-	default:
-		return fmt.Errorf("invalid modal response type: %s", modalType)
+
+		message, err := f(ctx, s, i, input)
+		if err != nil {
+			return err
+		}
+
+		m, err := s.GuildMember(i.GuildID, s.Me().ID)
+		if err != nil {
+			return fmt.Errorf("getting member: %w", err)
+		}
+		gs, energy, err := b.currentSettings(ctx, i.GuildID)
+		if err != nil {
+			return err
+		}
+
+		menu := b.configMenu(i.Locale, m.Nick, message, gs, energy)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Flags:      discordgo.MessageFlagsEphemeral | discordgo.MessageFlagsIsComponentsV2,
+			Components: &menu.Data.Components,
+		})
+		return err
 	}
+}
 
-	menu := b.configMenu(name, message)
-	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Flags:      discordgo.MessageFlagsEphemeral | discordgo.MessageFlagsIsComponentsV2,
-		Components: &menu.Data.Components,
-	})
-	return err
+// textInputValue gets the value of a text input from a modal.
+func textInputValue(input discordgo.MessageComponent) (string, error) {
+	ti, ok := input.(*discordgo.TextInput)
+	if !ok {
+		return "", fmt.Errorf("malformed interaction data: expected text input, got %T", input)
+	}
+	return ti.Value, nil
+}
+
+func (b *Bot) energyButton(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, id command.CustomID) (string, error) {
+	setting, err := id.Arg(0)
+	if err != nil {
+		return "", err
+	}
+	delta, err := id.Int(1)
+	if err != nil {
+		return "", err
+	}
+	return b.adjustEnergy(ctx, i.Locale, i.GuildID, setting, int(delta))
 }
 
-func (b *Bot) configMessageComponentHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	log.Ctx(ctx).Info().Msg("config message component handler")
+func (b *Bot) togglePronouns(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, id command.CustomID) (string, error) {
+	gs, err := b.synth.GuildSettings(ctx, i.GuildID)
+	if err != nil {
+		return "", fmt.Errorf("getting guild settings: %w", err)
+	}
+	gs.PronounRewrite = !gs.PronounRewrite
+	err = gs.Save(ctx)
+	if err != nil {
+		return "", fmt.Errorf("saving guild settings: %w", err)
+	}
+	if gs.PronounRewrite {
+		return i18n.T(i.Locale, "configure.pronouns.enabled"), nil
+	}
+	return i18n.T(i.Locale, "configure.pronouns.disabled"), nil
+}
 
-	m, err := s.GuildMember(i.GuildID, s.State.User.ID)
+func (b *Bot) selectHandlerRole(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, id command.CustomID) (string, error) {
+	gs, err := b.synth.GuildSettings(ctx, i.GuildID)
 	if err != nil {
-		return fmt.Errorf("getting member: %w", err)
+		return "", fmt.Errorf("getting guild settings: %w", err)
 	}
-	currentName := m.Nick
+	data := i.MessageComponentData()
+	gs.HandlerRoleID = ""
+	message := i18n.T(i.Locale, "configure.handler_role.removed")
+	if len(data.Values) > 0 {
+		gs.HandlerRoleID = data.Values[0]
+		message = i18n.T(i.Locale, "configure.handler_role.set", gs.HandlerRoleID)
+	}
+	err = gs.Save(ctx)
+	if err != nil {
+		return "", fmt.Errorf("saving guild settings: %w", err)
+	}
+	return message, nil
+}
 
-	message := "Unknown interaction"
+func (b *Bot) selectAllowLogging(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, id command.CustomID) (string, error) {
 	data := i.MessageComponentData()
-	switch data.CustomID {
-	case "max_m10":
-		b.maxEnergy -= 10
-		message = "Maximum energy set to " + strconv.Itoa(b.maxEnergy)
-	case "max_m1":
-		b.maxEnergy -= 1
-		message = "Maximum energy set to " + strconv.Itoa(b.maxEnergy)
-	case "max_p1":
-		b.maxEnergy += 1
-		message = "Maximum energy set to " + strconv.Itoa(b.maxEnergy)
-	case "max_p10":
-		b.maxEnergy += 10
-		message = "Maximum energy set to " + strconv.Itoa(b.maxEnergy)
-	case "regen_m10":
-		b.regen -= 10
-		message = "Energy regen set to " + strconv.Itoa(b.regen)
-	case "regen_m1":
-		b.regen -= 1
-		message = "Energy regen set to " + strconv.Itoa(b.regen)
-	case "regen_p1":
-		b.regen += 1
-		message = "Energy regen set to " + strconv.Itoa(b.regen)
-	case "regen_p10":
-		b.regen += 10
-		message = "Energy regen set to " + strconv.Itoa(b.regen)
-	case "allow_logging":
-		// TODO not suck
-		b.synth.AllowLogging = data.Values[0] == "true"
-		err = b.synth.Save(ctx)
-		if err != nil {
-			return fmt.Errorf("saving synth: %w", err)
-		}
-	case "synth_name":
-		// TODO figure out how to delete the original response, or edit it after the modal, if possible
-		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseModal,
-			Data: &discordgo.InteractionResponseData{
-				CustomID: fmt.Sprintf("synthName_%s", i.Interaction.ID),
-				Title:    "Set Synth Name",
-				Flags:    discordgo.MessageFlagsIsComponentsV2,
-				Components: []discordgo.MessageComponent{
-					discordgo.Label{
-						Label:       "Synth Name",
-						Description: "Enter a new name for this synth",
-						Component: discordgo.TextInput{
-							CustomID:  "synth_name",
-							Style:     discordgo.TextInputShort,
-							MinLength: 1,
-							MaxLength: 32,
-							Required:  true,
-							Value:     m.Nick,
-						},
+	if len(data.Values) == 0 {
+		return "", fmt.Errorf("malformed interaction data: no logging option selected")
+	}
+	// only the one column is saved, so that this can't undo a lock that was changed at the same time
+	err := b.synth.SetAllowLogging(ctx, data.Values[0] == "true")
+	if err != nil {
+		return "", fmt.Errorf("saving synth: %w", err)
+	}
+	if b.synth.AllowLogging {
+		return i18n.T(i.Locale, "configure.logging.allowed"), nil
+	}
+	return i18n.T(i.Locale, "configure.logging.disallowed"), nil
+}
+
+// TODO figure out how to delete the original response when a modal is opened, or edit it after the modal, if possible
+
+func (b *Bot) pronounsModal(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	gs, err := b.synth.GuildSettings(ctx, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("getting guild settings: %w", err)
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: command.NewCustomID(configPronouns, i.ID).String(),
+			Title:    i18n.T(i.Locale, "configure.pronouns.modal_title"),
+			Flags:    discordgo.MessageFlagsIsComponentsV2,
+			Components: []discordgo.MessageComponent{
+				discordgo.TextDisplay{
+					Content: i18n.T(i.Locale, "configure.pronouns.help"),
+				},
+				discordgo.Label{
+					Label:       i18n.T(i.Locale, "configure.pronouns.label"),
+					Description: i18n.T(i.Locale, "configure.pronouns.label_help"),
+					Component: discordgo.TextInput{
+						CustomID:  "pronoun_replacements",
+						Style:     discordgo.TextInputParagraph,
+						MinLength: 1,
+						MaxLength: 2000,
+						Required:  true,
+						Value:     speech.FormatPronounReplacements(pronounReplacements(ctx, gs)),
 					},
 				},
 			},
-		})
-	case "avatar":
-		// TODO figure out how to delete the original response, or edit it after the modal, if possible
-		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseModal,
-			Data: &discordgo.InteractionResponseData{
-				CustomID: fmt.Sprintf("avatar_%s", i.Interaction.ID),
-				Title:    "Set Bio",
-				Flags:    discordgo.MessageFlagsIsComponentsV2,
-				Components: []discordgo.MessageComponent{
-					discordgo.TextDisplay{
-						Content: "TODO current avatar?",
+		},
+	})
+}
+
+func (b *Bot) templateModal(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	gs, err := b.synth.GuildSettings(ctx, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("getting guild settings: %w", err)
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: command.NewCustomID(configTemplate, i.ID).String(),
+			Title:    i18n.T(i.Locale, "configure.template.modal_title"),
+			Flags:    discordgo.MessageFlagsIsComponentsV2,
+			Components: []discordgo.MessageComponent{
+				discordgo.TextDisplay{
+					Content: templateHelp(i.Locale),
+				},
+				discordgo.Label{
+					Label:       i18n.T(i.Locale, "configure.template.label"),
+					Description: i18n.T(i.Locale, "configure.template.example"),
+					Component: discordgo.TextInput{
+						CustomID:  "template",
+						Style:     discordgo.TextInputParagraph,
+						MaxLength: speech.MaxMessageLength / 2,
+						Required:  false,
+						Value:     gs.Template,
 					},
-					discordgo.Label{
-						Label:       "New avatar",
-						Description: "Upload a new avatar for this synth",
-						Component: discordgo.FileUpload{
-							CustomID:  "avatar",
-							Required:  new(true),
-							MaxValues: 1,
-						},
+				},
+			},
+		},
+	})
+}
+
+func (b *Bot) synthNameModal(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	m, err := s.GuildMember(i.GuildID, s.Me().ID)
+	if err != nil {
+		return fmt.Errorf("getting member: %w", err)
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: command.NewCustomID(configSynthName, i.ID).String(),
+			Title:    i18n.T(i.Locale, "configure.name.modal_title"),
+			Flags:    discordgo.MessageFlagsIsComponentsV2,
+			Components: []discordgo.MessageComponent{
+				discordgo.Label{
+					Label:       i18n.T(i.Locale, "configure.name.label"),
+					Description: i18n.T(i.Locale, "configure.name.help"),
+					Component: discordgo.TextInput{
+						CustomID:  "synth_name",
+						Style:     discordgo.TextInputShort,
+						MinLength: 1,
+						MaxLength: 32,
+						Required:  true,
+						Value:     m.Nick,
 					},
 				},
 			},
-		})
-	case "bio":
-		// TODO figure out how to delete the original response, or edit it after the modal, if possible
-		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseModal,
-			Data: &discordgo.InteractionResponseData{
-				CustomID: fmt.Sprintf("bio_%s", i.Interaction.ID),
-				Title:    "Set Bio",
-				Flags:    discordgo.MessageFlagsIsComponentsV2,
-				Components: []discordgo.MessageComponent{
-					discordgo.TextDisplay{
-						Content: "It is not currently possible to display the current bio here due to intentional Discord privacy controls.",
+		},
+	})
+}
+
+func (b *Bot) avatarModal(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: command.NewCustomID(configAvatar, i.ID).String(),
+			Title:    i18n.T(i.Locale, "configure.avatar.modal_title"),
+			Flags:    discordgo.MessageFlagsIsComponentsV2,
+			Components: []discordgo.MessageComponent{
+				discordgo.TextDisplay{
+					Content: i18n.T(i.Locale, "configure.avatar.current"),
+				},
+				discordgo.Label{
+					Label:       i18n.T(i.Locale, "configure.avatar.label"),
+					Description: i18n.T(i.Locale, "configure.avatar.help"),
+					Component: discordgo.FileUpload{
+						CustomID:  "avatar",
+						Required:  new(true),
+						MaxValues: 1,
 					},
-					discordgo.Label{
-						Label:       "Bio",
-						Description: "Enter a new bio for this synth",
-						Component: discordgo.TextInput{
-							CustomID:  "synth_bio",
-							Style:     discordgo.TextInputParagraph,
-							MinLength: 1,
-							MaxLength: 200,
-							Required:  true,
-						},
+				},
+			},
+		},
+	})
+}
+
+func (b *Bot) bioModal(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: command.NewCustomID(configBio, i.ID).String(),
+			Title:    i18n.T(i.Locale, "configure.bio.modal_title"),
+			Flags:    discordgo.MessageFlagsIsComponentsV2,
+			Components: []discordgo.MessageComponent{
+				discordgo.TextDisplay{
+					Content: i18n.T(i.Locale, "configure.bio.current"),
+				},
+				discordgo.Label{
+					Label:       i18n.T(i.Locale, "configure.bio.label"),
+					Description: i18n.T(i.Locale, "configure.bio.help"),
+					Component: discordgo.TextInput{
+						CustomID:  "synth_bio",
+						Style:     discordgo.TextInputParagraph,
+						MinLength: 1,
+						MaxLength: 200,
+						Required:  true,
 					},
 				},
 			},
-		})
+		},
+	})
+}
+
+func (b *Bot) submitPronouns(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, input discordgo.MessageComponent) (string, error) {
+	value, err := textInputValue(input)
+	if err != nil {
+		return "", err
+	}
+	return b.setPronounReplacements(ctx, i.Locale, i.GuildID, value)
+}
+
+func (b *Bot) submitTemplate(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, input discordgo.MessageComponent) (string, error) {
+	value, err := textInputValue(input)
+	if err != nil {
+		return "", err
+	}
+	return b.setTemplate(ctx, i.Locale, i.GuildID, value)
+}
+
+func (b *Bot) submitSynthName(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, input discordgo.MessageComponent) (string, error) {
+	name, err := textInputValue(input)
+	if err != nil {
+		return "", err
+	}
+	err = s.GuildMemberNickname(i.GuildID, "@me", name)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("new_name", name).Msg("Error setting new name")
+		return i18n.T(i.Locale, "configure.name.failed", err), nil
+	}
+	return i18n.T(i.Locale, "configure.name.set", name), nil
+}
+
+func (b *Bot) submitBio(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, input discordgo.MessageComponent) (string, error) {
+	bio, err := textInputValue(input)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.GuildCurrentMemberEdit(i.GuildID, &discordgo.GuildCurrentMemberParams{
+		Bio: &bio,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("new_bio", bio).Msg("Error setting new bio")
+		return i18n.T(i.Locale, "configure.bio.failed", err), nil
+	}
+	return i18n.T(i.Locale, "configure.bio.updated"), nil
+}
+
+func (b *Bot) submitAvatar(ctx context.Context, s discord.Session, i *discordgo.InteractionCreate, input discordgo.MessageComponent) (string, error) {
+	avatar, ok := input.(*discordgo.FileUpload)
+	if !ok || len(avatar.Values) != 1 {
+		return "", fmt.Errorf("malformed interaction data: expected one uploaded file")
+	}
+	data := i.ModalSubmitData()
+	att, ok := data.Resolved.Attachments[avatar.Values[0]]
+	if !ok {
+		return "", fmt.Errorf("malformed interaction data: attachment %s not resolved", avatar.Values[0])
+	}
+
+	// the avatar isn't changed, so it can be tried again right away
+	if att.Size > maxAvatarSize {
+		command.CancelCooldown(ctx)
+		return i18n.T(i.Locale, "configure.avatar.too_large", maxAvatarSize, att.Size), nil
+	} else if att.ContentType != "image/png" && att.ContentType != "image/jpeg" {
+		command.CancelCooldown(ctx)
+		return i18n.T(i.Locale, "configure.avatar.wrong_type", att.ContentType), nil
 	}
 
-	menu := b.configMenu(currentName, message)
-	menu.Type = discordgo.InteractionResponseUpdateMessage
-	return s.InteractionRespond(i.Interaction, menu)
+	log.Ctx(ctx).Info().Msg("changing avatar")
+	body, err := s.RequestWithBucketID("GET", att.URL, nil, "ephemeral-attachments")
+	if err != nil {
+		return "", fmt.Errorf("downloading avatar: %w", err)
+	}
+	b64 := base64.StdEncoding.EncodeToString(body)
+	_, err = s.UserUpdate("", fmt.Sprintf("data:%s;base64,%s", att.ContentType, b64), "")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Error updating avatar")
+		return i18n.T(i.Locale, "configure.avatar.failed"), nil
+	}
+	return i18n.T(i.Locale, "configure.avatar.updated"), nil
 }
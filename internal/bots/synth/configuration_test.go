@@ -0,0 +1,111 @@
+package synth
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// newGuildBot makes a Synth for owner with its commands registered, in a guild with a handler role.
+func newGuildBot(t *testing.T, owner *discordgo.User) (*Bot, *discordtest.Session) {
+	t.Helper()
+	b, s := newTestBot(t, owner)
+	b.buildCommands(t.Context())
+	err := b.cmdGroup.Register(t.Context(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMember("guild", &discordgo.Member{User: s.Me(), Nick: "synth"})
+
+	gs, err := b.synth.GuildSettings(t.Context(), "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs.HandlerRoleID = "handler-role"
+	err = gs.Save(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, s
+}
+
+// moderator makes an interaction come from someone with the Manage Server permission.
+func moderator(i *discordgo.InteractionCreate) *discordgo.InteractionCreate {
+	i.Member.Permissions = discordgo.PermissionManageGuild
+	return i
+}
+
+// roleHolder makes an interaction come from someone with the guild's handler role.
+func roleHolder(i *discordgo.InteractionCreate) *discordgo.InteractionCreate {
+	i.Member.Roles = []string{"handler-role"}
+	return i
+}
+
+// allowed handles an interaction, and reports whether it got past the policy.
+func allowed(t *testing.T, b *Bot, s *discordtest.Session, i *discordgo.InteractionCreate) bool {
+	t.Helper()
+	b.cmdGroup.Handler(s, i)
+	r := s.Response(i.ID)
+	if r == nil {
+		t.Fatal("the interaction was not responded to")
+	}
+	return r.Data == nil || r.Data.Content != i18n.T(i.Locale, "command.unauthorized")
+}
+
+// policyTest is an interaction, and whether it should be allowed.
+type policyTest struct {
+	name string
+	i    *discordgo.InteractionCreate
+	want bool
+}
+
+// testPolicies checks each interaction in order, as some of them change who is allowed to do what.
+func testPolicies(t *testing.T, b *Bot, s *discordtest.Session, tests []policyTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowed(t, b, s, tt.i); got != tt.want {
+				t.Errorf("allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigPolicies(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newGuildBot(t, owner)
+
+	u := discordtest.NewUser("someone")
+	testPolicies(t, b, s, []policyTest{
+		{"moderator changing logging", moderator(discordtest.Select(u, "guild", configAllowLogging, "true")), false},
+		{"owner changing logging", discordtest.Select(owner, "guild", configAllowLogging, "true"), true},
+		{"moderator changing avatar", moderator(discordtest.Button(u, "guild", configAvatar)), false},
+		{"handler role changing avatar", roleHolder(discordtest.Button(u, "guild", configAvatar)), false},
+		{"handler role changing handler role", roleHolder(discordtest.Select(u, "guild", configHandlerRole, "other-role")), false},
+		{"handler role changing name", roleHolder(discordtest.Button(u, "guild", configSynthName)), true},
+		// last, as it changes who has the handler role
+		{"moderator changing handler role", moderator(discordtest.Select(u, "guild", configHandlerRole, "other-role")), true},
+	})
+}
+
+func TestLockPolicies(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newGuildBot(t, owner)
+
+	u := discordtest.NewUser("someone")
+	lock := func(u *discordgo.User, sub string) *discordgo.InteractionCreate {
+		return discordtest.Command(u, "guild", "lock", discordtest.Subcommand(sub))
+	}
+	// the lock applies in every guild, so nobody is trusted with it just for being trusted in one of them
+	testPolicies(t, b, s, []policyTest{
+		{"moderator locking", moderator(lock(u, "engage")), false},
+		{"handler role locking", roleHolder(lock(u, "engage")), false},
+		{"handler role checking the lock", roleHolder(lock(u, "status")), true},
+		{"owner locking", lock(owner, "engage"), true},
+		{"moderator releasing", moderator(lock(u, "release")), false},
+		{"owner releasing", lock(owner, "release"), true},
+	})
+}
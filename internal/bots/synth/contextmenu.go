@@ -0,0 +1,231 @@
+package synth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// editMessageModal is the prefix of the custom ID of the modal for editing a message. Its arguments are the channel and
+// message IDs.
+const editMessageModal = "edit_message"
+
+func (b *Bot) buildContextMenuCommands() {
+	// the Synth is the owner's voice, so nobody else can put words in its mouth
+	b.cmdGroup.MessageCommand("Edit Synth Message").
+		Handler(b.editMessageHandler).
+		Policy(b.ownerOnly).
+		InteractionContext(discordgo.InteractionContextGuild).
+		Build()
+	b.cmdGroup.Modal(editMessageModal).
+		Handler(b.editMessageSubmit).
+		Policy(b.ownerOnly).
+		Build()
+
+	b.cmdGroup.MessageCommand("Delete Synth Message").
+		Handler(b.deleteMessageHandler).
+		Policy(b.controllers).
+		InteractionContext(discordgo.InteractionContextGuild).
+		Build()
+
+	// anyone can report a message
+	b.cmdGroup.MessageCommand("Report Synth Message").
+		Handler(b.reportMessageHandler).
+		InteractionContext(discordgo.InteractionContextGuild).
+		Build()
+
+	b.cmdGroup.UserCommand("View Synth Info").
+		Handler(b.synthInfoHandler).
+		InteractionContext(discordgo.InteractionContextGuild).
+		Build()
+}
+
+// targetSynthMessage gets the message a message command was used on, responding to the interaction if it was not sent
+// by this Synth. nil is returned in that case.
+func (b *Bot) targetSynthMessage(s discord.Session, i *discordgo.InteractionCreate, opts *command.Options) (*discordgo.Message, error) {
+	m, err := opts.TargetMessage()
+	if err != nil {
+		return nil, err
+	}
+	if m.Author == nil || m.Author.ID != s.Me().ID {
+		return nil, b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.not_synth"))
+	}
+	return m, nil
+}
+
+func (b *Bot) editMessageHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("edit message handler")
+
+	m, err := b.targetSynthMessage(s, i, opts)
+	if m == nil || err != nil {
+		return err
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: command.NewCustomID(editMessageModal, m.ChannelID, m.ID).String(),
+			Title:    i18n.T(i.Locale, "message.edit.modal_title"),
+			Flags:    discordgo.MessageFlagsIsComponentsV2,
+			Components: []discordgo.MessageComponent{
+				discordgo.TextDisplay{
+					// the message has already had the template applied, so it can't be edited in place
+					Content: i18n.T(i.Locale, "message.edit.help"),
+				},
+				discordgo.Label{
+					Label:       i18n.T(i.Locale, "message.edit.label"),
+					Description: i18n.T(i.Locale, "message.edit.label_help"),
+					Component: discordgo.TextInput{
+						CustomID:  "content",
+						Style:     discordgo.TextInputParagraph,
+						MinLength: 1,
+						MaxLength: maxPhraseLength,
+						Required:  true,
+					},
+				},
+			},
+		},
+	})
+}
+
+func (b *Bot) editMessageSubmit(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("edit message submit handler")
+
+	channelID, err := id.Arg(0)
+	if err != nil {
+		return err
+	}
+	messageID, err := id.Arg(1)
+	if err != nil {
+		return err
+	}
+
+	var content string
+	for _, c := range i.ModalSubmitData().Components {
+		if label, ok := c.(*discordgo.Label); ok {
+			content, err = textInputValue(label.Component)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.edit.empty"))
+	}
+
+	// edits are free, like editing by replying
+	content, _, _, err = b.compose(ctx, s, i.GuildID, content, &discordgo.Message{}, false)
+	var refused *refusedError
+	if errors.Is(err, errMuted) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.muted"))
+	} else if errors.As(err, &refused) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, refused.message(i.Locale))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.edit.failed"))
+		return fmt.Errorf("composing message: %w", err)
+	}
+
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel: channelID,
+		ID:      messageID,
+		Content: new(content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Parse: []discordgo.AllowedMentionType{
+				discordgo.AllowedMentionTypeUsers,
+				discordgo.AllowedMentionTypeRoles,
+			},
+		},
+	})
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.edit.failed"))
+		return fmt.Errorf("editing message: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.edit.done"))
+}
+
+func (b *Bot) deleteMessageHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("delete message handler")
+
+	m, err := b.targetSynthMessage(s, i, opts)
+	if m == nil || err != nil {
+		return err
+	}
+
+	err = s.ChannelMessageDelete(m.ChannelID, m.ID)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.delete.failed"))
+		return fmt.Errorf("deleting message: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.delete.done"))
+}
+
+func (b *Bot) reportMessageHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("report message handler")
+
+	m, err := b.targetSynthMessage(s, i, opts)
+	if m == nil || err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", i.GuildID, m.ChannelID, m.ID)
+	// there's no interaction from the owner to take their locale from
+	err = b.notifyOwner(s, i18n.T(i18n.DefaultLocale, "message.report.notice", u.ID, link, m.Content))
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.report.failed"))
+		return fmt.Errorf("notifying owner: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.report.done"))
+}
+
+func (b *Bot) synthInfoHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("synth info handler")
+
+	target, err := opts.TargetUser()
+	if err != nil {
+		return err
+	}
+	if target.ID != s.Me().ID && target.ID != b.synth.DiscordUserID {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.info.not_synth"))
+	}
+
+	b.lockMu.Lock()
+	mode := b.synth.ActiveLock(time.Now())
+	until := b.synth.LockedUntil
+	b.lockMu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(i.Locale, "message.info.owner", s.Me().ID, b.synth.DiscordUserID) + "\n")
+	sb.WriteString(describeLock(i.Locale, mode, until))
+
+	if i.GuildID != "" {
+		gs, energy, err := b.currentSettings(ctx, i.GuildID)
+		if err != nil {
+			_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.info.failed"))
+			return err
+		}
+		if gs.MaxEnergy > 0 {
+			sb.WriteString("\n" + i18n.T(i.Locale, "message.info.energy", math.Floor(energy.Current), gs.MaxEnergy, gs.EnergyRegen))
+		}
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, sb.String())
+}
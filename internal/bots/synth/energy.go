@@ -0,0 +1,140 @@
+package synth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+const (
+	energyPerMessage    = 1
+	energyPerWord       = 1
+	energyPerAttachment = 5
+	energyPerSticker    = 2
+	// charsPerEnergy is how many characters of content cost one additional energy.
+	charsPerEnergy = 50
+)
+
+// energyCost calculates how much energy it costs to proxy a message with the given content, which may differ from the
+// content of the original message.
+func energyCost(content string, m *discordgo.Message) float64 {
+	cost := energyPerMessage +
+		energyPerWord*len(strings.Fields(content)) +
+		utf8.RuneCountInString(content)/charsPerEnergy +
+		energyPerAttachment*len(m.Attachments) +
+		energyPerSticker*len(m.StickerItems)
+	return float64(cost)
+}
+
+// regenerate updates the current energy to account for regeneration since it was last updated.
+func regenerate(e *database.Energy, gs *database.GuildSettings, now time.Time) {
+	if now.After(e.RegenAt) {
+		e.Current += now.Sub(e.RegenAt).Minutes() * float64(gs.EnergyRegen)
+	}
+	e.Current = math.Min(e.Current, float64(gs.MaxEnergy))
+	e.RegenAt = now
+}
+
+// setMaxEnergy changes the maximum energy, keeping the current energy within bounds.
+func setMaxEnergy(e *database.Energy, gs *database.GuildSettings, newMax int) {
+	regenerate(e, gs, time.Now())
+	if gs.MaxEnergy == 0 {
+		// energy enforcement is just now being enabled, so start out full
+		e.Current = float64(newMax)
+	}
+	gs.MaxEnergy = max(newMax, 0)
+	e.Current = math.Min(e.Current, float64(gs.MaxEnergy))
+}
+
+// timeUntil calculates how long until the given amount of energy is available. It returns false if it will never be
+// available.
+func timeUntil(e *database.Energy, gs *database.GuildSettings, cost float64) (time.Duration, bool) {
+	if cost > float64(gs.MaxEnergy) || gs.EnergyRegen <= 0 {
+		return 0, false
+	}
+	minutes := (cost - e.Current) / float64(gs.EnergyRegen)
+	return time.Duration(math.Ceil(minutes * float64(time.Minute))), true
+}
+
+// spendEnergy attempts to spend energy in the given guild. If there was not enough energy, no energy is spent, and a
+// message suitable for displaying to the owner is returned, in the given locale.
+func (b *Bot) spendEnergy(ctx context.Context, locale discordgo.Locale, gs *database.GuildSettings, cost float64) (bool, string, error) {
+	if gs.MaxEnergy == 0 {
+		// energy enforcement is disabled
+		return true, "", nil
+	}
+
+	b.energyMu.Lock()
+	defer b.energyMu.Unlock()
+
+	e, err := b.synth.Energy(ctx, gs.GuildID)
+	if err != nil {
+		return false, "", fmt.Errorf("getting energy: %w", err)
+	}
+
+	regenerate(e, gs, time.Now())
+	if e.Current < cost {
+		msg := i18n.T(locale, "energy.insufficient", cost, math.Floor(e.Current), gs.MaxEnergy)
+		if wait, ok := timeUntil(e, gs, cost); ok {
+			msg += " " + i18n.T(locale, "energy.wait", wait.Round(time.Second).String())
+		} else if cost > float64(gs.MaxEnergy) {
+			msg += " " + i18n.T(locale, "energy.too_expensive")
+		} else {
+			msg += " " + i18n.T(locale, "energy.no_regen")
+		}
+		return false, msg, nil
+	}
+
+	e.Current -= cost
+	err = e.Save(ctx)
+	if err != nil {
+		return false, "", fmt.Errorf("saving energy: %w", err)
+	}
+	return true, "", nil
+}
+
+// refundEnergy gives back energy that was spent on a message that couldn't be sent. Nothing is refunded if gs is nil,
+// such as outside of guilds.
+func (b *Bot) refundEnergy(ctx context.Context, gs *database.GuildSettings, spent float64) {
+	if gs == nil || gs.MaxEnergy == 0 || spent == 0 {
+		return
+	}
+
+	b.energyMu.Lock()
+	defer b.energyMu.Unlock()
+
+	e, err := b.synth.Energy(ctx, gs.GuildID)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error getting energy to refund")
+		return
+	}
+	regenerate(e, gs, time.Now())
+	e.Current = math.Min(e.Current+spent, float64(gs.MaxEnergy))
+	err = e.Save(ctx)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error refunding energy")
+	}
+}
+
+// notifyOwner sends a direct message to the owner of this Synth.
+func (b *Bot) notifyOwner(s discord.Session, msg string) error {
+	ch, err := s.UserChannelCreate(b.synth.DiscordUserID)
+	if err != nil {
+		return fmt.Errorf("creating DM channel: %w", err)
+	}
+	_, err = s.ChannelMessageSend(ch.ID, msg)
+	if err != nil {
+		return fmt.Errorf("sending DM: %w", err)
+	}
+	return nil
+}
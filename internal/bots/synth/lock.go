@@ -0,0 +1,302 @@
+package synth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/bots"
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+// maxLockMinutes is the longest a lock with a timer can last. Longer locks have to be released manually.
+const maxLockMinutes = 365 * 24 * 60
+
+// errMuted is returned by applyLock when the Synth is not allowed to say anything.
+var errMuted = errors.New("synth is muted")
+
+func (b *Bot) buildLockCommands() {
+	tr := i18n.Default()
+	lock := b.cmdGroup.Command("lock").
+		Localize(tr, "commands.lock").
+		Handler(b.lockHandler).
+		// the lock applies in every guild, so guild moderators can't change it
+		Policy(b.handlers).
+		Build()
+	engage := lock.Subcommand("engage").
+		Localize(tr, "commands.lock.engage").
+		Handler(b.lockEngageHandler).
+		Build()
+	engage.Option("mode").
+		Localize(tr, "commands.lock.engage.mode").
+		Type(discordgo.ApplicationCommandOptionString).
+		Choice("mute", string(database.LockMute)).
+		Choice("phrases", string(database.LockPhrases)).
+		Build()
+	engage.Option("minutes").
+		Localize(tr, "commands.lock.engage.minutes").
+		Type(discordgo.ApplicationCommandOptionInteger).
+		MinValue(1).
+		MaxValue(maxLockMinutes).
+		Build()
+	lock.Subcommand("release").
+		Localize(tr, "commands.lock.release").
+		Handler(b.lockReleaseHandler).
+		Build()
+	lock.Subcommand("status").
+		Localize(tr, "commands.lock.status").
+		Handler(b.lockStatusHandler).
+		Policy(b.controllers).
+		Build()
+}
+
+func (b *Bot) lockHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Warn().Msg("lock handler called")
+	return b.InteractionSimpleTextResponse(s, i.Interaction, "This shouldn't be reachable")
+}
+
+func (b *Bot) lockEngageHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("lock engage handler")
+
+	modeStr, err := opts.StringOr("mode", string(database.LockMute))
+	if err != nil {
+		return err
+	}
+	mode := database.LockMode(strings.ToLower(modeStr))
+	if mode != database.LockMute && mode != database.LockPhrases {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "lock.invalid_mode"))
+	}
+	var until *time.Time
+	if opts.Has("minutes") {
+		minutes, err := opts.Int("minutes")
+		if err != nil {
+			return err
+		}
+		if minutes < 1 || minutes > maxLockMinutes {
+			return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "lock.invalid_minutes", maxLockMinutes))
+		}
+		until = new(time.Now().Add(time.Duration(minutes) * time.Minute))
+	}
+
+	err = b.lock(ctx, s, mode, until)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "lock.lock_failed"))
+		return fmt.Errorf("locking synth: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, describeLock(i.Locale, mode, until))
+}
+
+func (b *Bot) lockReleaseHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("lock release handler")
+
+	b.lockMu.Lock()
+	wasLocked := b.synth.ActiveLock(time.Now()) != database.LockNone
+	b.lockMu.Unlock()
+	if !wasLocked {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "lock.not_locked"))
+	}
+
+	err := b.lock(ctx, s, database.LockNone, nil)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "lock.release_failed"))
+		return fmt.Errorf("unlocking synth: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "lock.released"))
+}
+
+func (b *Bot) lockStatusHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("lock status handler")
+
+	b.lockMu.Lock()
+	mode := b.synth.ActiveLock(time.Now())
+	until := b.synth.LockedUntil
+	b.lockMu.Unlock()
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, describeLock(i.Locale, mode, until))
+}
+
+// describeLock formats the state of a lock for display.
+func describeLock(locale discordgo.Locale, mode database.LockMode, until *time.Time) string {
+	var key string
+	switch mode {
+	case database.LockNone:
+		return i18n.T(locale, "lock.status.none")
+	case database.LockPhrases:
+		key = "lock.status.phrases"
+	default:
+		key = "lock.status.muted"
+	}
+	if until != nil {
+		return i18n.T(locale, key+"_until", until.Unix(), until.Unix())
+	}
+	return i18n.T(locale, key)
+}
+
+// lock changes the lock on this Synth, releasing it if mode is database.LockNone.
+func (b *Bot) lock(ctx context.Context, s discord.Session, mode database.LockMode, until *time.Time) error {
+	b.lockMu.Lock()
+	var err error
+	if mode == database.LockNone {
+		err = b.synth.Unlock(ctx)
+	} else {
+		err = b.synth.Lock(ctx, mode, until)
+	}
+	b.scheduleUnlock(s)
+	b.lockMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = b.updatePresence(s)
+	if err != nil {
+		// the lock itself still worked
+		log.Ctx(ctx).Err(err).Msg("Error updating presence")
+	}
+	return nil
+}
+
+// restoreLock resumes a lock that was in place when the Synth was last running.
+func (b *Bot) restoreLock(ctx context.Context, s discord.Session) {
+	b.lockMu.Lock()
+	b.scheduleUnlock(s)
+	b.lockMu.Unlock()
+
+	err := b.updatePresence(s)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error updating presence")
+	}
+}
+
+// scheduleUnlock starts a timer to release the current lock when it expires, replacing any existing timer. lockMu must
+// be held.
+func (b *Bot) scheduleUnlock(s discord.Session) {
+	if b.lockTimer != nil {
+		b.lockTimer.Stop()
+		b.lockTimer = nil
+	}
+	if b.synth.LockMode == database.LockNone || b.synth.LockedUntil == nil {
+		return
+	}
+
+	until := *b.synth.LockedUntil
+	b.lockTimer = time.AfterFunc(time.Until(until), func() {
+		b.expireLock(s, until)
+	})
+}
+
+// expireLock releases a lock when its timer runs out.
+func (b *Bot) expireLock(s discord.Session, until time.Time) {
+	ctx := b.loggerCtx(context.Background())
+	defer bots.Recover(ctx)
+
+	b.lockMu.Lock()
+	// the lock may have been changed since the timer was started
+	if b.synth.LockMode == database.LockNone || b.synth.LockedUntil == nil || !b.synth.LockedUntil.Equal(until) {
+		b.lockMu.Unlock()
+		return
+	}
+	// ActiveLock already treats the lock as released, so this is only cleaning up
+	err := b.synth.Unlock(ctx)
+	b.lockTimer = nil
+	b.lockMu.Unlock()
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error releasing expired lock")
+	}
+
+	err = b.updatePresence(s)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error updating presence")
+	}
+	// there's no interaction to take the owner's locale from
+	err = b.notifyOwner(s, i18n.T(i18n.DefaultLocale, "lock.expired"))
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("Error notifying owner")
+	}
+}
+
+// applyLock restricts what the Synth says according to its lock. errMuted is returned if it may not say anything,
+// and a *speech.RejectedError if it may not say this.
+func (b *Bot) applyLock(ctx context.Context, content string, m *discordgo.Message) (string, error) {
+	b.lockMu.Lock()
+	mode := b.synth.ActiveLock(time.Now())
+	b.lockMu.Unlock()
+
+	switch mode {
+	case database.LockMute:
+		return "", errMuted
+	case database.LockPhrases:
+		if len(m.Attachments) > 0 || len(m.StickerItems) > 0 || m.Poll != nil {
+			return "", &speech.RejectedError{Reason: "only canned phrases are permitted"}
+		}
+		phrases, err := b.lockPhrases(ctx)
+		if err != nil {
+			return "", err
+		}
+		sm := &speech.Message{Content: content}
+		err = (&speech.Phrases{Phrases: phrases}).Apply(sm)
+		if err != nil {
+			return "", err
+		}
+		return sm.Content, nil
+	default:
+		return content, nil
+	}
+}
+
+// lockPhrases gets the phrases the Synth may say while it is locked to phrases: the permitted phrases in its library,
+// or the defaults if none are permitted.
+func (b *Bot) lockPhrases(ctx context.Context) ([]string, error) {
+	permitted, err := b.synth.PermittedPhrases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting permitted phrases: %w", err)
+	}
+	if len(permitted) == 0 {
+		return speech.DefaultPhrases, nil
+	}
+
+	phrases := make([]string, len(permitted))
+	for i, p := range permitted {
+		phrases[i] = p.Text
+	}
+	return phrases, nil
+}
+
+// updatePresence sets the Synth's presence to mirror its owner's, with an indicator while it is locked.
+func (b *Bot) updatePresence(s discord.Session) error {
+	b.lockMu.Lock()
+	data := b.presence
+	locked := b.synth.ActiveLock(time.Now()) != database.LockNone
+	b.lockMu.Unlock()
+
+	if locked {
+		activities := []*discordgo.Activity{{
+			Name: "Custom Status",
+			Type: discordgo.ActivityTypeCustom,
+			// the presence is the same for everyone, so it can only be in one language
+			State: i18n.T(i18n.DefaultLocale, "lock.presence"),
+		}}
+		// there can only be one custom status, so the owner's is hidden
+		for _, a := range data.Activities {
+			if a.Type != discordgo.ActivityTypeCustom {
+				activities = append(activities, a)
+			}
+		}
+		data.Activities = activities
+	}
+
+	return s.UpdateStatusComplex(data)
+}
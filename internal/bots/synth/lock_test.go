@@ -0,0 +1,145 @@
+package synth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// showsLock returns if the Synth's presence shows that it is locked.
+func showsLock(s *discordtest.Session) bool {
+	for _, a := range s.Status().Activities {
+		if a.Type == discordgo.ActivityTypeCustom && a.State == i18n.T(i18n.DefaultLocale, "lock.presence") {
+			return true
+		}
+	}
+	return false
+}
+
+// lockMode gets the mode the Synth is locked in, which may have expired.
+func lockMode(b *Bot) database.LockMode {
+	b.lockMu.Lock()
+	defer b.lockMu.Unlock()
+	return b.synth.LockMode
+}
+
+// stopLockTimer stops the timer for a lock that would outlast the test.
+func stopLockTimer(b *Bot) {
+	b.lockMu.Lock()
+	defer b.lockMu.Unlock()
+	if b.lockTimer != nil {
+		b.lockTimer.Stop()
+	}
+}
+
+// waitForDM waits for the Synth to DM its owner, failing the test if it takes too long.
+func waitForDM(t *testing.T, s *discordtest.Session) *discordgo.Message {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if sent := s.Sent(); len(sent) > 0 {
+			return sent[0]
+		}
+	}
+	t.Fatal("the owner was not notified")
+	return nil
+}
+
+func TestLockPresence(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+	b.presence.Activities = []*discordgo.Activity{
+		{Name: "Custom Status", Type: discordgo.ActivityTypeCustom, State: "beep"},
+		{Name: "a game", Type: discordgo.ActivityTypeGame},
+	}
+
+	err := b.lock(t.Context(), s, database.LockMute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activities := s.Status().Activities
+	if !showsLock(s) || len(activities) != 2 || activities[1].Name != "a game" {
+		t.Errorf("locked presence has activities %+v, want the lock and the owner's game", activities)
+	}
+
+	err = b.lock(t.Context(), s, database.LockNone, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activities = s.Status().Activities
+	if showsLock(s) || len(activities) != 2 || activities[0].State != "beep" {
+		t.Errorf("unlocked presence has activities %+v, want the owner's", activities)
+	}
+}
+
+func TestLockExpires(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newTestBot(t, owner)
+
+	err := b.lock(t.Context(), s, database.LockPhrases, new(time.Now().Add(50*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !showsLock(s) {
+		t.Error("the lock is not shown")
+	}
+
+	if dm := waitForDM(t, s); dm.Content != i18n.T(i18n.DefaultLocale, "lock.expired") {
+		t.Errorf("the owner was told %q", dm.Content)
+	}
+	if mode := lockMode(b); mode != database.LockNone {
+		t.Errorf("lock mode is %s after it expired", mode)
+	}
+	if showsLock(s) {
+		t.Error("the lock is still shown after it expired")
+	}
+}
+
+func TestRestoreLock(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		until time.Time
+		// expired is whether the lock expired while the Synth wasn't running.
+		expired bool
+	}{
+		{"unexpired", time.Now().Add(time.Hour), false},
+		{"expired", time.Now().Add(-time.Minute), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			owner := discordtest.NewUser("owner")
+			b, s := newTestBot(t, owner)
+			err := b.synth.Lock(t.Context(), database.LockMute, &tc.until)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { stopLockTimer(b) })
+
+			b.restoreLock(t.Context(), s)
+
+			if tc.expired {
+				waitForDM(t, s)
+				if mode := lockMode(b); mode != database.LockNone {
+					t.Errorf("lock mode is %s after it expired", mode)
+				}
+				if showsLock(s) {
+					t.Error("an expired lock is shown")
+				}
+				return
+			}
+
+			if !showsLock(s) {
+				t.Error("the restored lock is not shown")
+			}
+			b.lockMu.Lock()
+			scheduled := b.lockTimer != nil
+			b.lockMu.Unlock()
+			if !scheduled {
+				t.Error("the restored lock will not expire")
+			}
+		})
+	}
+}
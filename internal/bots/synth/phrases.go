@@ -0,0 +1,326 @@
+package synth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+// maxPhraseLength is the longest a phrase can be, leaving room for a template.
+const maxPhraseLength = speech.MaxMessageLength / 2
+
+func (b *Bot) buildPhraseCommands() {
+	tr := i18n.Default()
+	phrase := b.cmdGroup.Command("phrase").
+		Localize(tr, "commands.phrase").
+		Handler(b.phraseHandler).
+		// the library is used in every guild, and decides what the Synth can say while locked, so guild moderators
+		// can't change it
+		Policy(b.handlers).
+		Build()
+	add := phrase.Subcommand("add").
+		Localize(tr, "commands.phrase.add").
+		Handler(b.phraseAddHandler).
+		Build()
+	add.Option("text").
+		Localize(tr, "commands.phrase.add.text").
+		Type(discordgo.ApplicationCommandOptionString).
+		Required().
+		MaxLength(maxPhraseLength).
+		Build()
+	add.Option("permitted").
+		Localize(tr, "commands.phrase.add.permitted").
+		Type(discordgo.ApplicationCommandOptionBoolean).
+		Build()
+	remove := phrase.Subcommand("remove").
+		Localize(tr, "commands.phrase.remove").
+		Handler(b.phraseRemoveHandler).
+		Build()
+	remove.Option("phrase").
+		Localize(tr, "commands.phrase.remove.phrase").
+		Type(discordgo.ApplicationCommandOptionString).
+		Required().
+		Autocomplete(b.phraseSuggestions).
+		Build()
+	permit := phrase.Subcommand("permit").
+		Localize(tr, "commands.phrase.permit").
+		Handler(b.phrasePermitHandler).
+		Build()
+	permit.Option("phrase").
+		Localize(tr, "commands.phrase.permit.phrase").
+		Type(discordgo.ApplicationCommandOptionString).
+		Required().
+		Autocomplete(b.phraseSuggestions).
+		Build()
+	permit.Option("permitted").
+		Localize(tr, "commands.phrase.permit.permitted").
+		Type(discordgo.ApplicationCommandOptionBoolean).
+		Required().
+		Build()
+	phrase.Subcommand("list").
+		Localize(tr, "commands.phrase.list").
+		Handler(b.phraseListHandler).
+		Policy(b.controllers).
+		Build()
+	say := phrase.Subcommand("say").
+		Localize(tr, "commands.phrase.say").
+		Handler(b.phraseSayHandler).
+		// the Synth is the owner's voice, so nobody else can put words in its mouth
+		Policy(b.ownerOnly).
+		Build()
+	say.Option("phrase").
+		Localize(tr, "commands.phrase.say.phrase").
+		Type(discordgo.ApplicationCommandOptionString).
+		Required().
+		Autocomplete(b.phraseSuggestions).
+		Build()
+}
+
+func (b *Bot) phraseHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Warn().Msg("phrase handler called")
+	return b.InteractionSimpleTextResponse(s, i.Interaction, "This shouldn't be reachable")
+}
+
+func (b *Bot) phraseAddHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("phrase add handler")
+
+	text, err := opts.String("text")
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	permitted, err := opts.BoolOr("permitted", false)
+	if err != nil {
+		return err
+	}
+
+	if text == "" {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.empty"))
+	} else if utf8.RuneCountInString(text) > maxPhraseLength {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.too_long", maxPhraseLength))
+	}
+
+	p, err := b.synth.AddPhrase(ctx, text, permitted)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.save_failed"))
+		return fmt.Errorf("adding phrase: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.added", describePhrase(i.Locale, p)))
+}
+
+func (b *Bot) phraseRemoveHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("phrase remove handler")
+
+	value, err := opts.String("phrase")
+	if err != nil {
+		return err
+	}
+	p, err := b.resolvePhrase(ctx, value)
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.not_found"))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.load_failed"))
+		return err
+	}
+
+	err = b.synth.DeletePhrase(ctx, p.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.not_found"))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.remove_failed"))
+		return fmt.Errorf("deleting phrase: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.removed"))
+}
+
+func (b *Bot) phrasePermitHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("phrase permit handler")
+
+	value, err := opts.String("phrase")
+	if err != nil {
+		return err
+	}
+	permitted, err := opts.Bool("permitted")
+	if err != nil {
+		return err
+	}
+	p, err := b.resolvePhrase(ctx, value)
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.not_found"))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.load_failed"))
+		return err
+	}
+
+	err = b.synth.SetPhrasePermitted(ctx, p.ID, permitted)
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.not_found"))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.save_failed"))
+		return fmt.Errorf("saving phrase: %w", err)
+	}
+	p.Permitted = permitted
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.updated", describePhrase(i.Locale, p)))
+}
+
+func (b *Bot) phraseListHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("phrase list handler")
+
+	phrases, err := b.synth.Phrases(ctx)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.load_failed"))
+		return fmt.Errorf("getting phrases: %w", err)
+	}
+	if len(phrases) == 0 {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.none"))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(i.Locale, "phrase.list"))
+	anyPermitted := false
+	for _, p := range phrases {
+		sb.WriteString("\n* ")
+		sb.WriteString(describePhrase(i.Locale, &p))
+		anyPermitted = anyPermitted || p.Permitted
+	}
+	if !anyPermitted {
+		sb.WriteString("\n-# " + i18n.T(i.Locale, "phrase.none_permitted"))
+	}
+
+	content := sb.String()
+	if utf8.RuneCountInString(content) > speech.MaxMessageLength {
+		content = string([]rune(content)[:speech.MaxMessageLength-1]) + "…"
+	}
+	return b.InteractionSimpleTextResponse(s, i.Interaction, content)
+}
+
+func (b *Bot) phraseSayHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("phrase say handler")
+
+	value, err := opts.String("phrase")
+	if err != nil {
+		return err
+	}
+	p, err := b.resolvePhrase(ctx, value)
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.not_found"))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.load_failed"))
+		return err
+	}
+
+	// phrases go through the same restrictions as everything else the Synth says
+	content, gs, cost, err := b.compose(ctx, s, i.GuildID, p.Text, &discordgo.Message{}, true)
+	var refused *refusedError
+	if errors.Is(err, errMuted) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "message.muted"))
+	} else if errors.As(err, &refused) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, refused.message(i.Locale))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.say_failed"))
+		return fmt.Errorf("composing phrase: %w", err)
+	}
+
+	var spent float64
+	if gs != nil {
+		ok, msg, err := b.spendEnergy(ctx, i.Locale, gs, cost)
+		if err != nil {
+			// don't silence the Synth because of our own problems
+			log.Ctx(ctx).Err(err).Msg("Error spending energy")
+		} else if !ok {
+			return b.InteractionSimpleTextResponse(s, i.Interaction, msg)
+		} else {
+			spent = cost
+		}
+	}
+
+	_, err = s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+		Content: content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Parse: []discordgo.AllowedMentionType{
+				discordgo.AllowedMentionTypeUsers,
+				discordgo.AllowedMentionTypeRoles,
+			},
+		},
+	})
+	if err != nil {
+		b.refundEnergy(ctx, gs, spent)
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.say_failed"))
+		return fmt.Errorf("sending phrase: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "phrase.said", p.Text))
+}
+
+// describePhrase formats a phrase for display.
+func describePhrase(locale discordgo.Locale, p *database.Phrase) string {
+	desc := fmt.Sprintf("`%d`: %s", p.ID, p.Text)
+	if p.Permitted {
+		desc += " " + i18n.T(locale, "phrase.permitted")
+	}
+	return desc
+}
+
+// resolvePhrase finds a phrase in the library from the value of an option, which is the ID of the phrase if it was
+// chosen from the suggestions, or the text of the phrase if it was typed out. ErrNotFound is returned if there is no
+// such phrase.
+func (b *Bot) resolvePhrase(ctx context.Context, value string) (*database.Phrase, error) {
+	phrases, err := b.synth.Phrases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting phrases: %w", err)
+	}
+
+	value = strings.TrimSpace(value)
+	id, idErr := strconv.ParseUint(value, 10, 64)
+	for _, p := range phrases {
+		if idErr == nil && p.ID == id {
+			return &p, nil
+		}
+	}
+	for _, p := range phrases {
+		if strings.EqualFold(p.Text, value) {
+			return &p, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+// phraseSuggestions suggests phrases from the library that contain what has been typed so far.
+func (b *Bot) phraseSuggestions(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options, typed string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	phrases, err := b.synth.Phrases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting phrases: %w", err)
+	}
+
+	typed = strings.ToLower(strings.TrimSpace(typed))
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, p := range phrases {
+		if strings.Contains(strings.ToLower(p.Text), typed) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  p.Text,
+				Value: strconv.FormatUint(p.ID, 10),
+			})
+		}
+	}
+	return choices, nil
+}
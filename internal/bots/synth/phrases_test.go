@@ -0,0 +1,88 @@
+package synth
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+func TestPhrasePolicies(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newGuildBot(t, owner)
+
+	u := discordtest.NewUser("someone")
+	add := func(u *discordgo.User) *discordgo.InteractionCreate {
+		return discordtest.Command(u, "guild", "phrase", discordtest.Subcommand("add", discordtest.String("text", "Yes.")))
+	}
+	phrase := func(u *discordgo.User, sub string, opts ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+		return discordtest.Command(u, "guild", "phrase", discordtest.Subcommand(sub, opts...))
+	}
+	testPolicies(t, b, s, []policyTest{
+		{"moderator adding", moderator(add(u)), false},
+		{"handler role adding", roleHolder(add(u)), false},
+		{"owner adding", add(owner), true},
+		{"moderator permitting", moderator(phrase(u, "permit", discordtest.String("phrase", "Yes."), discordtest.Bool("permitted", true))), false},
+		{"moderator removing", moderator(phrase(u, "remove", discordtest.String("phrase", "Yes."))), false},
+		{"moderator listing", moderator(phrase(u, "list")), true},
+		{"moderator saying", moderator(phrase(u, "say", discordtest.String("phrase", "Yes."))), false},
+	})
+}
+
+func TestPhraseLibrary(t *testing.T) {
+	owner := discordtest.NewUser("owner")
+	b, s := newGuildBot(t, owner)
+
+	run := func(sub string, opts ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		i := discordtest.Command(owner, "guild", "phrase", discordtest.Subcommand(sub, opts...))
+		b.cmdGroup.Handler(s, i)
+		r := s.Response(i.ID)
+		if r == nil || r.Data == nil {
+			t.Fatal("the interaction was not responded to")
+		}
+		return r.Data.Content
+	}
+
+	if got := run("list"); got != i18n.T(discordgo.EnglishUS, "phrase.none") {
+		t.Errorf("empty library listed as %q", got)
+	}
+	if got := run("add", discordtest.String("text", "  Yes.  ")); !strings.Contains(got, "Yes.") {
+		t.Errorf("adding replied %q", got)
+	}
+	run("add", discordtest.String("text", "No."), discordtest.Bool("permitted", true))
+	if got := run("add", discordtest.String("text", " ")); got != i18n.T(discordgo.EnglishUS, "phrase.empty") {
+		t.Errorf("adding an empty phrase replied %q", got)
+	}
+
+	phrases, err := b.synth.Phrases(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phrases) != 2 || phrases[0].Text != "Yes." || phrases[0].Permitted || !phrases[1].Permitted {
+		t.Fatalf("library is %+v, want the two phrases", phrases)
+	}
+	got := run("list")
+	if !strings.Contains(got, "Yes.") || !strings.Contains(got, "No. "+i18n.T(discordgo.EnglishUS, "phrase.permitted")) {
+		t.Errorf("listed %q", got)
+	}
+
+	// phrases can be given by their text, or by their ID when chosen from the suggestions
+	if got := run("remove", discordtest.String("phrase", "yes.")); got != i18n.T(discordgo.EnglishUS, "phrase.removed") {
+		t.Errorf("removing by text replied %q", got)
+	}
+	if got := run("remove", discordtest.String("phrase", "Yes.")); got != i18n.T(discordgo.EnglishUS, "phrase.not_found") {
+		t.Errorf("removing it again replied %q", got)
+	}
+	id := discordtest.String("phrase", strconv.FormatUint(phrases[1].ID, 10))
+	if got := run("remove", id); got != i18n.T(discordgo.EnglishUS, "phrase.removed") {
+		t.Errorf("removing by ID replied %q", got)
+	}
+	if got := run("list"); got != i18n.T(discordgo.EnglishUS, "phrase.none") {
+		t.Errorf("library listed as %q after removing everything", got)
+	}
+}
@@ -0,0 +1,215 @@
+package synth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+func (b *Bot) buildRulesCommands() {
+	tr := i18n.Default()
+	rules := b.cmdGroup.Command("rules").
+		Localize(tr, "commands.rules").
+		Handler(b.rulesHandler).
+		Policy(b.controllers).
+		InteractionContext(discordgo.InteractionContextGuild).
+		Build()
+	add := rules.Subcommand("add").
+		Localize(tr, "commands.rules.add").
+		Handler(b.rulesAddHandler).
+		Build()
+	kind := add.Option("kind").
+		Localize(tr, "commands.rules.add.kind").
+		Type(discordgo.ApplicationCommandOptionString).
+		Required()
+	for _, k := range speech.Kinds {
+		kind.Choice(string(k), string(k))
+	}
+	kind.Build()
+	action := add.Option("action").
+		Localize(tr, "commands.rules.add.action").
+		Type(discordgo.ApplicationCommandOptionString)
+	for _, a := range speech.Actions {
+		action.Choice(string(a), string(a))
+	}
+	action.Build()
+	add.Option("value").
+		Localize(tr, "commands.rules.add.value").
+		Type(discordgo.ApplicationCommandOptionString).
+		Build()
+	remove := rules.Subcommand("remove").
+		Localize(tr, "commands.rules.remove").
+		Handler(b.rulesRemoveHandler).
+		Build()
+	remove.Option("id").
+		Localize(tr, "commands.rules.remove.id").
+		Type(discordgo.ApplicationCommandOptionInteger).
+		Required().
+		MinValue(1).
+		Build()
+	rules.Subcommand("list").
+		Localize(tr, "commands.rules.list").
+		Handler(b.rulesListHandler).
+		Build()
+}
+
+func (b *Bot) rulesHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	log.Ctx(ctx).Warn().Msg("rules handler called")
+	return b.InteractionSimpleTextResponse(s, i.Interaction, "This shouldn't be reachable")
+}
+
+func (b *Bot) rulesAddHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("rules add handler")
+
+	kindStr, err := opts.String("kind")
+	if err != nil {
+		return err
+	}
+	actionStr, err := opts.StringOr("action", "")
+	if err != nil {
+		return err
+	}
+	param, err := opts.StringOr("value", "")
+	if err != nil {
+		return err
+	}
+
+	kind := speech.Kind(strings.ToLower(kindStr))
+	action, err := speech.ParseAction(actionStr)
+	if err != nil {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.invalid_action"))
+	}
+	_, err = speech.New(kind, action, param)
+	if errors.Is(err, speech.ErrInvalidKind) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.invalid_kind"))
+	} else if errors.Is(err, speech.ErrInvalidParam) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.invalid_value", err))
+	} else if err != nil {
+		return err
+	}
+
+	r, err := b.synth.AddSpeechRule(ctx, i.GuildID, string(kind), string(action), param)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.add_failed"))
+		return fmt.Errorf("adding speech rule: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.added", describeRule(r)))
+}
+
+func (b *Bot) rulesRemoveHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("rules remove handler")
+
+	id, err := opts.Int("id")
+	if err != nil {
+		return err
+	}
+	if id < 1 {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.not_found"))
+	}
+	err = b.synth.DeleteSpeechRule(ctx, i.GuildID, uint64(id))
+	if errors.Is(err, database.ErrNotFound) {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.not_found"))
+	} else if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.remove_failed"))
+		return fmt.Errorf("deleting speech rule: %w", err)
+	}
+
+	return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.removed"))
+}
+
+func (b *Bot) rulesListHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+	ctx = b.loggerCtx(ctx)
+	log.Ctx(ctx).Info().Msg("rules list handler")
+
+	rules, err := b.synth.SpeechRules(ctx, i.GuildID)
+	if err != nil {
+		_ = b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.list_failed"))
+		return fmt.Errorf("getting speech rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return b.InteractionSimpleTextResponse(s, i.Interaction, i18n.T(i.Locale, "rules.none"))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(i.Locale, "rules.list"))
+	for _, r := range rules {
+		sb.WriteString("\n* ")
+		sb.WriteString(describeRule(&r))
+	}
+	return b.InteractionSimpleTextResponse(s, i.Interaction, sb.String())
+}
+
+// describeRule formats a rule for display.
+func describeRule(r *database.SpeechRule) string {
+	desc := fmt.Sprintf("`%d`: **%s** (%s)", r.ID, r.Kind, r.Action)
+	if r.Param != "" {
+		desc += ": `" + r.Param + "`"
+	}
+	return desc
+}
+
+// speechPipeline builds the speech rules for a guild.
+func (b *Bot) speechPipeline(ctx context.Context, gs *database.GuildSettings) (speech.Pipeline, error) {
+	rules, err := b.synth.SpeechRules(ctx, gs.GuildID)
+	if err != nil {
+		return nil, fmt.Errorf("getting speech rules: %w", err)
+	}
+
+	p := make(speech.Pipeline, 0, len(rules)+1)
+	if gs.PronounRewrite {
+		// rewrite first, so that rules are checked against what will actually be said
+		p = append(p, &speech.Pronouns{Replacements: pronounReplacements(ctx, gs)})
+	}
+	for _, r := range rules {
+		rule, err := speech.New(speech.Kind(r.Kind), speech.Action(r.Action), r.Param)
+		if err != nil {
+			// these are validated before they are saved, so this shouldn't happen
+			log.Ctx(ctx).Err(err).Uint64("rule_id", r.ID).Msg("Skipping invalid speech rule")
+			continue
+		}
+		p = append(p, rule)
+	}
+	return p, nil
+}
+
+// applySpeechRules applies the speech rules for a guild to the content of a message.
+func (b *Bot) applySpeechRules(ctx context.Context, gs *database.GuildSettings, content string) (*speech.Message, error) {
+	p, err := b.speechPipeline(ctx, gs)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &speech.Message{Content: content}
+	err = p.Apply(m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// pronounReplacements gets the pronoun replacements for a guild, falling back to the defaults.
+func pronounReplacements(ctx context.Context, gs *database.GuildSettings) map[string]string {
+	if gs.PronounReplacements == "" {
+		return speech.DefaultPronounReplacements
+	}
+	replacements, err := speech.ParsePronounReplacements(gs.PronounReplacements)
+	if err != nil {
+		// these are validated before they are saved, so this shouldn't happen
+		log.Ctx(ctx).Err(err).Msg("Invalid pronoun replacements; using defaults")
+		return speech.DefaultPronounReplacements
+	}
+	return replacements
+}
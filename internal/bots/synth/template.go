@@ -0,0 +1,71 @@
+package synth
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+// renderContent produces the final content of a message that has been through the speech rules, wrapping it in the
+// guild's template if there is one. cost is how much energy the message will cost, so the template can show how much
+// energy will be left.
+func (b *Bot) renderContent(ctx context.Context, s discord.Session, gs *database.GuildSettings, sm *speech.Message, cost float64) (string, error) {
+	out := &speech.Message{
+		Content:     sm.Content,
+		Annotations: sm.Annotations,
+	}
+
+	if gs.Template != "" {
+		t, err := speech.ParseTemplate(gs.Template)
+		if err != nil {
+			return "", fmt.Errorf("parsing template: %w", err)
+		}
+
+		d := speech.TemplateData{
+			Content: sm.Content,
+			Name:    b.displayName(s, gs.GuildID),
+			ID:      s.Me().Username,
+			OwnerID: b.synth.DiscordUserID,
+			Time:    time.Now(),
+		}
+		if gs.MaxEnergy > 0 {
+			e, err := b.synth.Energy(ctx, gs.GuildID)
+			if err != nil {
+				return "", fmt.Errorf("getting energy: %w", err)
+			}
+			regenerate(e, gs, d.Time)
+			d.Energy = new(max(e.Current-cost, 0))
+		}
+
+		out.Content, err = t.Render(d)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	content := out.String()
+	if n := utf8.RuneCountInString(content); n > speech.MaxMessageLength {
+		return "", fmt.Errorf("%w: %d characters", speech.ErrTooLong, n)
+	}
+	return content, nil
+}
+
+// displayName gets the name the Synth is displayed with in a guild.
+func (b *Bot) displayName(s discord.Session, guildID string) string {
+	m, err := s.StateMember(guildID, s.Me().ID)
+	if err != nil {
+		m, err = s.GuildMember(guildID, s.Me().ID)
+	}
+	if err == nil && m.Nick != "" {
+		return m.Nick
+	}
+	if s.Me().GlobalName != "" {
+		return s.Me().GlobalName
+	}
+	return s.Me().Username
+}
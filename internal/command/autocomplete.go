@@ -0,0 +1,111 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+	"github.com/ajanata/synthos/internal/discord"
+)
+
+// AutocompleteHandler suggests values for an option. typed is what has been entered for the option so far, and opts
+// are the other options that have been entered.
+type AutocompleteHandler func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options, typed string) ([]*discordgo.ApplicationCommandOptionChoice, error)
+
+// Discord limits on autocomplete suggestions.
+const (
+	maxChoices          = 25
+	maxChoiceNameLength = 100
+)
+
+// autocomplete finds the AutocompleteHandler for the focused option of the command or subcommand that is being used,
+// including subcommands in groups, and suggests values with it.
+func (c *Command) autocomplete(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options) error {
+	name := c.path()
+	p := c.policy
+	options := i.ApplicationCommandData().Options
+	autocompletes := c.autocompletes
+
+	if subcmd := c.invoked(options); subcmd != nil {
+		name = subcmd.name
+		p = subcmd.policy
+		autocompletes = subcmd.autocompletes
+	} else if len(options) > 0 && (options[0].Type == discordgo.ApplicationCommandOptionSubCommand ||
+		options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup) {
+		return fmt.Errorf("no such subcommand %s", options[0].Name)
+	}
+	options = leafOptions(options)
+
+	for _, opt := range options {
+		if !opt.Focused {
+			continue
+		}
+		h, ok := autocompletes[opt.Name]
+		if !ok {
+			// probably a stale registration; the user just doesn't get suggestions
+			log.Ctx(ctx).Warn().Str("path", name).Str("option", opt.Name).Msg("No autocomplete handler for option")
+			return respondChoices(s, i.Interaction, nil)
+		}
+		return c.grp.suggest(ctx, name, p, h, s, u, i, opts, fmt.Sprint(opt.Value))
+	}
+	log.Ctx(ctx).Warn().Str("path", name).Msg("No focused option to autocomplete")
+	return respondChoices(s, i.Interaction, nil)
+}
+
+// suggest responds to an autocomplete interaction with the suggestions from h, if the policy allows the user to use
+// the command. Users that are not allowed get no suggestions, rather than an error.
+func (g *Group) suggest(ctx context.Context, name string, p authorizer.Policy, h AutocompleteHandler, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options, typed string) error {
+	allowed := true
+	if p != nil {
+		r := authorizer.NewRequest(g.owner, u, i.Interaction)
+		r.Command = name
+		r.Autocomplete = true
+		d, err := p.Decide(ctx, r)
+		if err != nil {
+			_ = respondChoices(s, i.Interaction, nil)
+			return fmt.Errorf("authorizing: %w", err)
+		}
+		allowed = d.Effect == authorizer.Allow
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	if allowed {
+		var err error
+		choices, err = h(ctx, s, u, i, opts, typed)
+		if err != nil {
+			_ = respondChoices(s, i.Interaction, nil)
+			return err
+		}
+	} else {
+		log.Ctx(ctx).Trace().Msg("Not authorized for suggestions")
+	}
+
+	return respondChoices(s, i.Interaction, choices)
+}
+
+// respondChoices responds to an autocomplete interaction, keeping the suggestions within Discord's limits.
+func respondChoices(s discord.Session, i *discordgo.Interaction, choices []*discordgo.ApplicationCommandOptionChoice) error {
+	if len(choices) > maxChoices {
+		choices = choices[:maxChoices]
+	}
+	for _, c := range choices {
+		if utf8.RuneCountInString(c.Name) > maxChoiceNameLength {
+			c.Name = string([]rune(c.Name)[:maxChoiceNameLength-1]) + "…"
+		}
+	}
+
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("autocomplete response: %w", err)
+	}
+	return nil
+}
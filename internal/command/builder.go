@@ -1,54 +1,132 @@
 package command
 
 import (
+	"time"
+
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
 )
 
 type Builder struct {
-	cmd     *discordgo.ApplicationCommand
-	handler Handler
-	grp     *Group
+	cmd      *discordgo.ApplicationCommand
+	handler  Handler
+	policy   authorizer.Policy
+	guilds   []string
+	cooldown *cooldown
+	grp      *Group
 }
 
-func newBuilder(grp *Group, name string) *Builder {
+func newBuilder(grp *Group, name string, t discordgo.ApplicationCommandType) *Builder {
 	return &Builder{
 		cmd: &discordgo.ApplicationCommand{
 			Name: name,
+			Type: t,
 		},
 		grp: grp,
 	}
 }
 
 func (b *Builder) Build() *Command {
-	if b.cmd.Name == "" {
-		log.Panic().Msg("Command name is required")
+	var names, descriptions map[discordgo.Locale]string
+	if b.cmd.NameLocalizations != nil {
+		names = *b.cmd.NameLocalizations
+	}
+	if b.cmd.DescriptionLocalizations != nil {
+		descriptions = *b.cmd.DescriptionLocalizations
 	}
-	if b.cmd.Description == "" {
-		log.Panic().Msg("Command description is required")
+	if b.cmd.Type == discordgo.ChatApplicationCommand {
+		validate("Command", b.cmd.Name, b.cmd.Description)
+		validateLocalizations("Command", b.cmd.Name, names, descriptions)
+	} else {
+		validateContextMenu(b.cmd.Name, b.cmd.Description)
+		for _, n := range names {
+			validateContextMenu(n, "")
+		}
+		if len(descriptions) > 0 {
+			log.Panic().Str("name", b.cmd.Name).Msg("Context menu commands cannot have a description")
+		}
 	}
 	if b.handler == nil {
 		log.Panic().Msg("Command handler is required")
 	}
 
 	c := &Command{
-		cmd:     b.cmd,
-		handler: b.handler,
+		cmd:      b.cmd,
+		policy:   b.policy,
+		guilds:   b.guilds,
+		cooldown: b.cooldown,
+		grp:      b.grp,
+	}
+	// cooldowns are checked after authorizing, so that people that can't use the command can't hold it up
+	c.handler = b.grp.authorize(c.path(), b.policy, b.grp.withCooldown(c.path(), b.cooldown, b.handler))
+	for _, other := range b.grp.commands {
+		if other.key() == c.key() {
+			log.Panic().Str("command", c.path()).Msg("Duplicate command name")
+		}
 	}
 	b.grp.commands = append(b.grp.commands, c)
 	return c
 }
 
+// Description sets the description of a slash command. Context menu commands cannot have a description.
 func (b *Builder) Description(d string) *Builder {
 	b.cmd.Description = d
 	return b
 }
 
+// Localize sets the description of a slash command from key+".description" in l, and the name and description in other
+// languages from key+".name" and key+".description". Context menu commands only have their name localized, as they
+// have no description.
+func (b *Builder) Localize(l Localizer, key string) *Builder {
+	d, names, descriptions := localize(l, key)
+	if names != nil {
+		b.cmd.NameLocalizations = &names
+	}
+	if b.cmd.Type == discordgo.ChatApplicationCommand {
+		if d != "" {
+			b.cmd.Description = d
+		}
+		if descriptions != nil {
+			b.cmd.DescriptionLocalizations = &descriptions
+		}
+	}
+	return b
+}
+
 func (b *Builder) Handler(h Handler) *Builder {
 	b.handler = h
 	return b
 }
 
+// Policy sets the policy that decides who can use the command, and its subcommands unless they set their own. Commands
+// without a policy can be used by anyone.
+func (b *Builder) Policy(p authorizer.Policy) *Builder {
+	b.policy = p
+	return b
+}
+
+// Cooldown limits how often the command, and its subcommands unless they set their own, can be used. scope decides who
+// shares the cooldown.
+func (b *Builder) Cooldown(d time.Duration, scope CooldownScope) *Builder {
+	b.cooldown = &cooldown{d: d, scope: scope}
+	return b
+}
+
+// SharedCooldown is like Cooldown, but the cooldown is shared with everything else that uses the same key, such as a
+// command and a component that do the same thing.
+func (b *Builder) SharedCooldown(key string, d time.Duration, scope CooldownScope) *Builder {
+	b.cooldown = &cooldown{d: d, scope: scope, key: key}
+	return b
+}
+
+// Guilds registers the command only in the given guilds, instead of where the Group registers its commands.
+func (b *Builder) Guilds(guildIDs ...string) *Builder {
+	b.guilds = guildIDs
+	return b
+}
+
 func (b *Builder) InteractionContext(c ...discordgo.InteractionContextType) *Builder {
 	b.cmd.Contexts = &c
 	return b
@@ -4,44 +4,144 @@ import (
 	"context"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+	"github.com/ajanata/synthos/internal/discord"
 )
 
 type Command struct {
-	cmd      *discordgo.ApplicationCommand
-	subcmds  []*Subcommand
-	handler  Handler
-	handlers map[string]Handler
+	cmd     *discordgo.ApplicationCommand
+	subcmds []*Subcommand
+	groups  []*SubcommandGroup
+	handler Handler
+	// invokable are the subcommands, by their names relative to this command, such as "sub" or "group sub".
+	invokable     map[string]*Subcommand
+	autocompletes map[string]AutocompleteHandler
+	policy        authorizer.Policy
+	// guilds are the guilds the command is registered in, if it is not registered where the Group registers commands.
+	guilds []string
+	// cooldown limits how often the command, and its subcommands that do not set their own, can be used.
+	cooldown *cooldown
+	grp      *Group
 }
 
-type Handler func(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error
+// Handler handles a command. opts are the options the command or subcommand was used with.
+type Handler func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options) error
 
-func (c *Command) cmdHandler(ctx context.Context, s *discordgo.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+func (c *Command) cmdHandler(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options) error {
 	// check for subcommand
-	options := i.ApplicationCommandData().Options
-	if len(options) > 0 {
-		subcmd := options[0].Name
-		if h, ok := c.handlers[subcmd]; ok {
-			return h(ctx, s, u, i)
-		}
+	if subcmd := c.invoked(i.ApplicationCommandData().Options); subcmd != nil {
+		return subcmd.handler(ctx, s, u, i, opts)
 	}
 
 	// fall back
-	return c.handler(ctx, s, u, i)
+	return c.handler(ctx, s, u, i, opts)
+}
+
+// invoked finds the subcommand that was used, if any.
+func (c *Command) invoked(options []*discordgo.ApplicationCommandInteractionDataOption) *Subcommand {
+	if len(options) == 0 {
+		return nil
+	}
+	key := options[0].Name
+	if options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup && len(options[0].Options) > 0 {
+		key += " " + options[0].Options[0].Name
+	}
+	return c.invokable[key]
+}
+
+// hasSubcommands returns if the command has any subcommands or subcommand groups.
+func (c *Command) hasSubcommands() bool {
+	return len(c.subcmds) > 0 || len(c.groups) > 0
 }
 
-func (c *Command) addOption(opt *discordgo.ApplicationCommandOption) {
-	c.cmd.Options = append(c.cmd.Options, opt)
+// isContextMenu returns if the command is a user or message command, rather than a slash command.
+func (c *Command) isContextMenu() bool {
+	return c.cmd.Type != discordgo.ChatApplicationCommand
+}
+
+func (c *Command) addOption(opt *discordgo.ApplicationCommandOption, ac AutocompleteHandler) {
+	if c.isContextMenu() {
+		log.Panic().Str("command", c.cmd.Name).Msg("Context menu commands cannot have options")
+	}
+	if c.hasSubcommands() {
+		log.Panic().Str("command", c.cmd.Name).Msg("Commands with subcommands cannot have options")
+	}
+	c.cmd.Options = appendOption(c.path(), c.cmd.Options, opt)
+	if ac != nil {
+		if c.autocompletes == nil {
+			c.autocompletes = make(map[string]AutocompleteHandler)
+		}
+		c.autocompletes[opt.Name] = ac
+	}
 }
 
 func (c *Command) addSubcommand(s *Subcommand) {
-	c.cmd.Options = append(c.cmd.Options, s.subcmd)
+	if c.isContextMenu() {
+		log.Panic().Str("command", c.cmd.Name).Msg("Context menu commands cannot have subcommands")
+	}
+	if len(c.cmd.Options) > 0 && !c.hasSubcommands() {
+		log.Panic().Str("command", c.cmd.Name).Msg("Commands with options cannot have subcommands")
+	}
+	c.cmd.Options = appendOption(c.path(), c.cmd.Options, s.subcmd)
 	c.subcmds = append(c.subcmds, s)
 }
 
+func (c *Command) addSubcommandGroup(g *SubcommandGroup) {
+	if c.isContextMenu() {
+		log.Panic().Str("command", c.cmd.Name).Msg("Context menu commands cannot have subcommand groups")
+	}
+	if len(c.cmd.Options) > 0 && !c.hasSubcommands() {
+		log.Panic().Str("command", c.cmd.Name).Msg("Commands with options cannot have subcommand groups")
+	}
+	c.cmd.Options = appendOption(c.path(), c.cmd.Options, g.opt)
+	c.groups = append(c.groups, g)
+}
+
+// path is the name of the command as the user sees it: "/name" for slash commands, and just the name for context menu
+// commands.
+func (c *Command) path() string {
+	if c.isContextMenu() {
+		return c.cmd.Name
+	}
+	return "/" + c.cmd.Name
+}
+
+// scopes are the guilds the command is registered in, with "" meaning it is global.
+func (c *Command) scopes() []string {
+	if len(c.guilds) > 0 {
+		return c.guilds
+	}
+	return []string{c.grp.guildID}
+}
+
+// key identifies the command among the commands in its Group. Commands of different types can have the same name.
+func (c *Command) key() commandKey {
+	return commandKey{c.cmd.Type, c.cmd.Name}
+}
+
+func (c *Command) inheritedPolicy() authorizer.Policy {
+	return c.policy
+}
+
+func (c *Command) inheritedCooldown() *cooldown {
+	return c.cooldown
+}
+
+func (c *Command) group() *Group {
+	return c.grp
+}
+
 func (c *Command) registerSubcommands() {
-	c.handlers = make(map[string]Handler)
+	c.invokable = make(map[string]*Subcommand)
 	for _, subcmd := range c.subcmds {
-		c.handlers[subcmd.subcmd.Name] = subcmd.handler
+		c.invokable[subcmd.subcmd.Name] = subcmd
+	}
+	for _, g := range c.groups {
+		for _, subcmd := range g.subcmds {
+			c.invokable[g.opt.Name+" "+subcmd.subcmd.Name] = subcmd
+		}
 	}
 }
 
@@ -62,3 +162,8 @@ func (c *Command) Option(name string) *OptionBuilder {
 func (c *Command) Subcommand(name string) *SubcommandBuilder {
 	return newSubcommandBuilder(c, name)
 }
+
+// SubcommandGroup starts building a group of subcommands, such as the "rules" in "/synth rules add".
+func (c *Command) SubcommandGroup(name string) *SubcommandGroupBuilder {
+	return newSubcommandGroupBuilder(c, name)
+}
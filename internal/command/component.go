@@ -0,0 +1,247 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+	"github.com/ajanata/synthos/internal/discord"
+)
+
+// customIDSeparator separates the prefix and arguments of a CustomID. They are escaped, so they can contain it.
+const customIDSeparator = ":"
+
+// maxCustomIDLength is the longest custom ID Discord allows.
+const maxCustomIDLength = 100
+
+var ErrCustomIDTooLong = errors.New("custom ID too long")
+var ErrMissingArgument = errors.New("missing custom ID argument")
+
+// CustomID identifies a message component or modal, and carries state for its handler. The prefix decides which
+// handler is used.
+type CustomID struct {
+	Prefix string
+	Args   []string
+}
+
+// NewCustomID creates a CustomID. The arguments are formatted with fmt.Sprint.
+func NewCustomID(prefix string, args ...any) CustomID {
+	id := CustomID{Prefix: prefix}
+	for _, arg := range args {
+		id.Args = append(id.Args, fmt.Sprint(arg))
+	}
+	return id
+}
+
+// ParseCustomID decodes a custom ID that was encoded by CustomID.Encode.
+func ParseCustomID(s string) (CustomID, error) {
+	parts := strings.Split(s, customIDSeparator)
+	for i, part := range parts {
+		var err error
+		parts[i], err = url.QueryUnescape(part)
+		if err != nil {
+			return CustomID{}, fmt.Errorf("invalid custom ID %s: %w", s, err)
+		}
+	}
+	return CustomID{Prefix: parts[0], Args: parts[1:]}, nil
+}
+
+// Encode encodes the CustomID for use in a component or modal. ErrCustomIDTooLong is returned if it is too long for
+// Discord.
+func (id CustomID) Encode() (string, error) {
+	parts := make([]string, 0, len(id.Args)+1)
+	parts = append(parts, url.QueryEscape(id.Prefix))
+	for _, arg := range id.Args {
+		parts = append(parts, url.QueryEscape(arg))
+	}
+	s := strings.Join(parts, customIDSeparator)
+	if len(s) > maxCustomIDLength {
+		return "", fmt.Errorf("%w: %d characters", ErrCustomIDTooLong, len(s))
+	}
+	return s, nil
+}
+
+// String encodes the CustomID, panicking if it is too long. Use Encode if the arguments come from users.
+func (id CustomID) String() string {
+	s, err := id.Encode()
+	if err != nil {
+		log.Panic().Err(err).Str("prefix", id.Prefix).Msg("Invalid custom ID")
+	}
+	return s
+}
+
+// Arg gets an argument by position.
+func (id CustomID) Arg(n int) (string, error) {
+	if n >= len(id.Args) {
+		return "", fmt.Errorf("%w: %d of %s", ErrMissingArgument, n, id.Prefix)
+	}
+	return id.Args[n], nil
+}
+
+// Int gets an integer argument by position.
+func (id CustomID) Int(n int) (int64, error) {
+	arg, err := id.Arg(n)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("argument %d of %s: %w", n, id.Prefix, err)
+	}
+	return v, nil
+}
+
+// ComponentHandler handles a message component interaction or a modal submission. id is the decoded custom ID of the
+// component or modal.
+type ComponentHandler func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id CustomID) error
+
+// route sends the interactions for components or modals with matching custom IDs to a handler.
+type route struct {
+	// prefix matches the prefix of a CustomID exactly, if set.
+	prefix string
+	// pattern matches the raw custom ID, if set.
+	pattern *regexp.Regexp
+	handler ComponentHandler
+}
+
+func (r *route) matches(raw string, id CustomID) bool {
+	if r.pattern != nil {
+		return r.pattern.MatchString(raw)
+	}
+	return r.prefix == id.Prefix
+}
+
+type ComponentBuilder struct {
+	grp      *Group
+	modal    bool
+	prefix   string
+	pattern  *regexp.Regexp
+	handler  ComponentHandler
+	policy   authorizer.Policy
+	cooldown *cooldown
+}
+
+// Component starts building a handler for message components whose CustomID has the given prefix.
+func (g *Group) Component(prefix string) *ComponentBuilder {
+	return &ComponentBuilder{grp: g, prefix: prefix}
+}
+
+// ComponentMatching starts building a handler for message components whose raw custom ID matches the pattern. Prefixes
+// are checked before patterns.
+func (g *Group) ComponentMatching(pattern *regexp.Regexp) *ComponentBuilder {
+	return &ComponentBuilder{grp: g, pattern: pattern}
+}
+
+// Modal starts building a handler for modals whose CustomID has the given prefix.
+func (g *Group) Modal(prefix string) *ComponentBuilder {
+	return &ComponentBuilder{grp: g, modal: true, prefix: prefix}
+}
+
+// ModalMatching starts building a handler for modals whose raw custom ID matches the pattern. Prefixes are checked
+// before patterns.
+func (g *Group) ModalMatching(pattern *regexp.Regexp) *ComponentBuilder {
+	return &ComponentBuilder{grp: g, modal: true, pattern: pattern}
+}
+
+func (b *ComponentBuilder) Handler(h ComponentHandler) *ComponentBuilder {
+	b.handler = h
+	return b
+}
+
+// Policy sets the policy that decides who can use the component or submit the modal. Components without a policy can
+// be used by anyone that can see them.
+func (b *ComponentBuilder) Policy(p authorizer.Policy) *ComponentBuilder {
+	b.policy = p
+	return b
+}
+
+// Cooldown limits how often the component can be used or the modal submitted. scope decides who shares the cooldown.
+func (b *ComponentBuilder) Cooldown(d time.Duration, scope CooldownScope) *ComponentBuilder {
+	b.cooldown = &cooldown{d: d, scope: scope}
+	return b
+}
+
+// SharedCooldown is like Cooldown, but the cooldown is shared with everything else that uses the same key, such as a
+// command and a component that do the same thing.
+func (b *ComponentBuilder) SharedCooldown(key string, d time.Duration, scope CooldownScope) *ComponentBuilder {
+	b.cooldown = &cooldown{d: d, scope: scope, key: key}
+	return b
+}
+
+func (b *ComponentBuilder) Build() {
+	if b.prefix == "" && b.pattern == nil {
+		log.Panic().Msg("Component prefix or pattern is required")
+	}
+	if b.handler == nil {
+		log.Panic().Str("prefix", b.prefix).Msg("Component handler is required")
+	}
+
+	routes := &b.grp.components
+	kind := "component"
+	if b.modal {
+		routes = &b.grp.modals
+		kind = "modal"
+	}
+	name := kind + " " + b.prefix
+	if b.pattern != nil {
+		name = kind + " " + b.pattern.String()
+	}
+
+	for _, r := range *routes {
+		if b.prefix != "" && r.prefix == b.prefix {
+			log.Panic().Str("prefix", b.prefix).Msg("Duplicate " + kind + " prefix")
+		}
+	}
+
+	r := &route{
+		prefix:  b.prefix,
+		pattern: b.pattern,
+		handler: b.grp.authorizeComponent(name, b.policy, b.grp.componentWithCooldown(name, b.cooldown, b.handler)),
+	}
+	// prefixes are checked first, as they are more specific
+	if b.prefix != "" {
+		*routes = append([]*route{r}, *routes...)
+	} else {
+		*routes = append(*routes, r)
+	}
+}
+
+// authorizeComponent wraps a component handler so that it is only called if the policy allows it. A nil policy allows
+// everyone.
+func (g *Group) authorizeComponent(name string, p authorizer.Policy, h ComponentHandler) ComponentHandler {
+	if p == nil {
+		return h
+	}
+
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id CustomID) error {
+		ok, err := g.allowed(ctx, name, p, s, u, i)
+		if !ok || err != nil {
+			return err
+		}
+		return h(ctx, s, u, i, id)
+	}
+}
+
+// handleComponent sends a component or modal interaction to the first matching route.
+func (g *Group) handleComponent(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, routes []*route, raw string) error {
+	id, err := ParseCustomID(raw)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range routes {
+		if r.matches(raw, id) {
+			return r.handler(ctx, s, u, i, id)
+		}
+	}
+	return fmt.Errorf("no handler for custom ID %s", raw)
+}
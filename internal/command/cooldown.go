@@ -0,0 +1,156 @@
+package command
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// CooldownScope is who shares a cooldown.
+type CooldownScope int
+
+const (
+	// PerUser cooldowns apply to each user separately.
+	PerUser CooldownScope = iota
+	// PerGuild cooldowns apply to everyone in a guild. Outside of guilds they apply to each user separately.
+	PerGuild
+	// PerGroup cooldowns apply to everyone using the Group's commands. Each Synth has its own Group, so this is a
+	// cooldown for the whole Synth.
+	PerGroup
+)
+
+// cooldown is how long must pass between uses of a command or component.
+type cooldown struct {
+	d     time.Duration
+	scope CooldownScope
+	// key is shared by everything that uses this cooldown, instead of its own name, if it is set.
+	key string
+}
+
+// cooldowns tracks when commands and components can be used again.
+type cooldowns struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// take starts a cooldown for key if it is not already cooling down, returning when it ends. Otherwise, how long until it
+// can be used again is returned.
+func (c *cooldowns) take(key string, d time.Duration, now time.Time) (time.Duration, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.until == nil {
+		c.until = make(map[string]time.Time)
+	}
+	// forget about expired cooldowns so that the map doesn't grow forever
+	for k, t := range c.until {
+		if !now.Before(t) {
+			delete(c.until, k)
+		}
+	}
+
+	if t, ok := c.until[key]; ok {
+		return t.Sub(now), time.Time{}
+	}
+	c.until[key] = now.Add(d)
+	return 0, c.until[key]
+}
+
+// release ends the cooldown for key that take started, unless it has been replaced since.
+func (c *cooldowns) release(key string, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.until[key].Equal(until) {
+		delete(c.until, key)
+	}
+}
+
+// cancelCooldownKey is the context key of the function that cancels the cooldown of the handler being run.
+type cancelCooldownKey struct{}
+
+// CancelCooldown ends the cooldown that was started for the handler being run, such as when it rejected the user's input
+// without doing anything. It does nothing if the handler has no cooldown.
+func CancelCooldown(ctx context.Context) {
+	if cancel, ok := ctx.Value(cancelCooldownKey{}).(*bool); ok {
+		*cancel = true
+	}
+}
+
+// cooldownKey identifies who shares a cooldown for the named command or component.
+func cooldownKey(name string, scope CooldownScope, u *discordgo.User, i *discordgo.InteractionCreate) string {
+	switch scope {
+	case PerGroup:
+		return name
+	case PerGuild:
+		if i.GuildID != "" {
+			return name + " guild:" + i.GuildID
+		}
+	}
+	return name + " user:" + u.ID
+}
+
+// startCooldown checks if the named command or component is cooling down, responding to the interaction if it is.
+// Otherwise, its cooldown is started, and the returned function must be called with the result of the handler. The
+// cooldown is ended if the handler failed or called CancelCooldown, so that it is only taken by successful uses.
+func (g *Group) startCooldown(ctx context.Context, name string, cd *cooldown, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) (context.Context, func(error), bool, error) {
+	if cd.key != "" {
+		name = cd.key
+	}
+	key := cooldownKey(name, cd.scope, u, i)
+	wait, until := g.cooldowns.take(key, cd.d, time.Now())
+	if wait > 0 {
+		log.Ctx(ctx).Debug().Dur("wait", wait).Msg("Cooling down")
+		return ctx, nil, true, respond(s, i.Interaction, i18n.T(i.Locale, "command.cooldown", math.Ceil(wait.Seconds())))
+	}
+
+	cancelled := new(bool)
+	ctx = context.WithValue(ctx, cancelCooldownKey{}, cancelled)
+	return ctx, func(err error) {
+		if err != nil || *cancelled {
+			g.cooldowns.release(key, until)
+		}
+	}, false, nil
+}
+
+// withCooldown wraps a handler so that it can only be used once per cooldown. A nil cooldown allows unlimited use.
+func (g *Group) withCooldown(name string, cd *cooldown, h Handler) Handler {
+	if cd == nil {
+		return h
+	}
+
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options) error {
+		ctx, done, cooling, err := g.startCooldown(ctx, name, cd, s, u, i)
+		if cooling || err != nil {
+			return err
+		}
+		err = h(ctx, s, u, i, opts)
+		done(err)
+		return err
+	}
+}
+
+// componentWithCooldown wraps a component handler so that it can only be used once per cooldown. A nil cooldown allows
+// unlimited use.
+func (g *Group) componentWithCooldown(name string, cd *cooldown, h ComponentHandler) ComponentHandler {
+	if cd == nil {
+		return h
+	}
+
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id CustomID) error {
+		ctx, done, cooling, err := g.startCooldown(ctx, name, cd, s, u, i)
+		if cooling || err != nil {
+			return err
+		}
+		err = h(ctx, s, u, i, id)
+		done(err)
+		return err
+	}
+}
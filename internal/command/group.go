@@ -2,87 +2,253 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
 )
 
 // Group is a grouping of Commands for a discordgo.Session. There should be only one Group per Session.
 type Group struct {
-	commands []*Command
-	handlers map[string]Handler
+	commands   []*Command
+	handlers   map[commandKey]Handler
+	byName     map[commandKey]*Command
+	components []*route
+	modals     []*route
+	middleware []Middleware
+	cooldowns  cooldowns
+	owner      string
+	// guildID is the guild commands are registered in, unless they specify their own guilds. They are global if it is
+	// empty.
+	guildID string
+}
+
+// commandKey identifies a command by its type and name.
+type commandKey struct {
+	t    discordgo.ApplicationCommandType
+	name string
 }
 
 func NewGroup() *Group {
 	return &Group{}
 }
 
+// ForOwner sets the ID of the user that owns the synth these commands are for, which is used to authorize commands.
+func (g *Group) ForOwner(owner string) *Group {
+	g.owner = owner
+	return g
+}
+
+// InGuild registers the commands in a single guild instead of globally, unless they specify their own guilds. Guild
+// commands are updated immediately, so this is useful when developing.
+func (g *Group) InGuild(guildID string) *Group {
+	g.guildID = guildID
+	return g
+}
+
+// authorize wraps a handler so that it is only called if the policy allows it. A nil policy allows everyone.
+func (g *Group) authorize(name string, p authorizer.Policy, h Handler) Handler {
+	if p == nil {
+		return h
+	}
+
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *Options) error {
+		ok, err := g.allowed(ctx, name, p, s, u, i)
+		if !ok || err != nil {
+			return err
+		}
+		return h(ctx, s, u, i, opts)
+	}
+}
+
+// allowed checks if the policy allows the user to use a command or component, responding to the interaction if not.
+func (g *Group) allowed(ctx context.Context, name string, p authorizer.Policy, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) (bool, error) {
+	r := authorizer.NewRequest(g.owner, u, i.Interaction)
+	r.Command = name
+	d, err := p.Decide(ctx, r)
+	if err != nil {
+		_ = respond(s, i.Interaction, i18n.T(i.Locale, "command.authorize_failed"))
+		return false, fmt.Errorf("authorizing: %w", err)
+	}
+	if d.Effect != authorizer.Allow {
+		log.Ctx(ctx).Info().Str("reason", d.Reason).Msg("Not authorized")
+		return false, respond(s, i.Interaction, i18n.T(i.Locale, "command.unauthorized"))
+	}
+
+	log.Ctx(ctx).Trace().Str("reason", d.Reason).Msg("Authorized")
+	return true, nil
+}
+
+// respond responds to an interaction with a simple message, which is only shown to the user if it was in a guild.
+func respond(s discord.Session, i *discordgo.Interaction, msg string) error {
+	var flags discordgo.MessageFlags
+	if i.Member != nil {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   flags,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("interaction response: %w", err)
+	}
+	return nil
+}
+
 func (g *Group) Command(name string) *Builder {
-	return newBuilder(g, name)
+	return newBuilder(g, name, discordgo.ChatApplicationCommand)
+}
+
+// UserCommand starts building a command that is used from the context menu of a user. Its name is shown as it is, so
+// it can have spaces and capital letters, like "View Info".
+func (g *Group) UserCommand(name string) *Builder {
+	return newBuilder(g, name, discordgo.UserApplicationCommand)
 }
 
-func (g *Group) Register(ctx context.Context, s *discordgo.Session) error {
+// MessageCommand starts building a command that is used from the context menu of a message. Its name is shown as it
+// is, so it can have spaces and capital letters, like "Report Message".
+func (g *Group) MessageCommand(name string) *Builder {
+	return newBuilder(g, name, discordgo.MessageApplicationCommand)
+}
+
+// Register registers the commands with Discord, and prepares to handle them. Only the commands that changed since
+// they were last registered are sent to Discord.
+func (g *Group) Register(ctx context.Context, s discord.Session) error {
 	log.Ctx(ctx).Trace().Msg("Registering commands")
-	g.handlers = make(map[string]Handler)
+	g.handlers = make(map[commandKey]Handler)
+	g.byName = make(map[commandKey]*Command)
 
-	var appCmds []*discordgo.ApplicationCommand
+	// the global commands are always synced, so that they are removed if they were moved to a guild
+	scopes := map[string][]*discordgo.ApplicationCommand{"": nil}
 	for _, c := range g.commands {
 		c.registerSubcommands()
-		appCmds = append(appCmds, c.cmd)
-		g.handlers[c.cmd.Name] = c.cmdHandler
+		for _, guildID := range c.scopes() {
+			scopes[guildID] = append(scopes[guildID], c.cmd)
+		}
+		g.handlers[c.key()] = c.cmdHandler
+		g.byName[c.key()] = c
 	}
 
-	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", appCmds)
-	if err != nil {
+	var errs []error
+	for guildID, cmds := range scopes {
+		err := syncCommands(ctx, s, guildID, cmds)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
 		return fmt.Errorf("adding commands: %w", err)
 	}
 
 	return nil
 }
 
-func (g *Group) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.Type != discordgo.InteractionApplicationCommand {
+// Unregister removes the commands from Discord, in every guild they were registered in. The Group can no longer handle
+// them afterward.
+func (g *Group) Unregister(ctx context.Context, s discord.Session) error {
+	log.Ctx(ctx).Trace().Msg("Unregistering commands")
+	g.handlers = make(map[commandKey]Handler)
+	g.byName = make(map[commandKey]*Command)
+
+	scopes := map[string]bool{"": true}
+	for _, c := range g.commands {
+		for _, guildID := range c.scopes() {
+			scopes[guildID] = true
+		}
+	}
+
+	var errs []error
+	for guildID := range scopes {
+		err := syncCommands(ctx, s, guildID, nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("removing commands: %w", err)
+	}
+
+	return nil
+}
+
+// Handler handles application command interactions, autocomplete interactions for their options, and message component
+// and modal interactions. They are passed through the middleware added with Use, and panics are recovered.
+func (g *Group) Handler(s discord.Session, i *discordgo.InteractionCreate) {
+	logger := log.With().Str("interaction_id", i.ID)
+	u := interactionUser(i)
+	if u != nil {
+		logger = logger.
+			Str("user_id", u.ID).
+			Str("username", u.Username)
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		logger = logger.Str("command", i.ApplicationCommandData().Name)
+	case discordgo.InteractionMessageComponent:
+		logger = logger.Str("custom_id", i.MessageComponentData().CustomID)
+	case discordgo.InteractionModalSubmit:
+		logger = logger.Str("custom_id", i.ModalSubmitData().CustomID)
+	default:
 		log.Trace().
 			Str("type", i.Type.String()).
 			Str("id", i.ID).
 			Msg("Received incorrect interaction type for a command; ignoring")
 		return
 	}
+	ctx := logger.Logger().WithContext(context.Background())
 
-	name := i.ApplicationCommandData().Name
-	if h, ok := g.handlers[name]; ok {
-		logger := log.With().
-			Str("command", name).
-			Logger()
-
-		var u *discordgo.User
-		// bot DMs have a User
-		if i.User != nil {
-			u = i.User
-			logger.With().
-				Str("user_id", i.User.ID).
-				Str("username", i.User.Username).
-				Logger()
-		}
+	h := g.dispatch
+	for j := len(g.middleware) - 1; j >= 0; j-- {
+		h = g.middleware[j](h)
+	}
+
+	ts := &trackedSession{Session: s}
+	err := recovered(ctx, h, ts, u, i)
+	if err != nil {
+		fail(ctx, ts, i, err)
+	}
+}
 
-		// but channel messages have a Member
-		if i.Member != nil && i.Member.User != nil {
-			u = i.Member.User
-			logger.With().
-				Str("user_id", i.Member.User.ID).
-				Str("username", i.Member.User.Username).
-				Logger()
+// dispatch sends an interaction to the handler for its command, component, or modal.
+func (g *Group) dispatch(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		key := commandKey{data.CommandType, data.Name}
+		h, ok := g.handlers[key]
+		if !ok {
+			return fmt.Errorf("no handler for command %s", data.Name)
 		}
 
-		ctx := logger.WithContext(context.Background())
-		err := h(ctx, s, u, i)
-		if err != nil {
-			log.Ctx(ctx).Err(err).Msg("Error in handler")
+		opts := newOptions(i)
+		if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			return g.byName[key].autocomplete(ctx, s, u, i, opts)
 		}
-	} else {
-		log.Warn().
-			Str("name", name).
-			Msg("No handler found for command")
+		return h(ctx, s, u, i, opts)
+	case discordgo.InteractionMessageComponent:
+		return g.handleComponent(ctx, s, u, i, g.components, i.MessageComponentData().CustomID)
+	case discordgo.InteractionModalSubmit:
+		return g.handleComponent(ctx, s, u, i, g.modals, i.ModalSubmitData().CustomID)
+	default:
+		return fmt.Errorf("unexpected interaction type %s", i.Type)
+	}
+}
+
+// interactionUser gets the user that caused an interaction.
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	// channel messages have a Member
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
 	}
+	// but bot DMs have a User
+	return i.User
 }
@@ -0,0 +1,476 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+	"github.com/ajanata/synthos/internal/command"
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+)
+
+// say responds to an interaction with a message.
+func say(s discord.Session, i *discordgo.Interaction, msg string) error {
+	return s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: msg},
+	})
+}
+
+// reply is a Handler that responds with a fixed message.
+func reply(msg string) command.Handler {
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+		return say(s, i.Interaction, msg)
+	}
+}
+
+// content gets the content of the response to an interaction, failing the test if there wasn't one.
+func content(t *testing.T, s *discordtest.Session, i *discordgo.InteractionCreate) string {
+	t.Helper()
+	r := s.Response(i.ID)
+	if r == nil || r.Data == nil {
+		t.Fatalf("interaction was not responded to")
+	}
+	return r.Data.Content
+}
+
+func TestRegisterOnlySyncsChanges(t *testing.T) {
+	ctx := t.Context()
+	s := discordtest.New("bot")
+
+	build := func(desc string) *command.Group {
+		g := command.NewGroup()
+		g.Command("ping").Description(desc).Handler(reply("pong")).Build()
+		g.MessageCommand("Report").Handler(reply("reported")).Build()
+		return g
+	}
+
+	if err := build("Ping the bot").Register(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Commands("")); n != 2 {
+		t.Fatalf("registered %d commands, want 2", n)
+	}
+
+	if err := build("Ping the bot").Register(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Calls("ApplicationCommandCreate"); n != 2 {
+		t.Errorf("created commands %d times, want 2", n)
+	}
+	if n := s.Calls("ApplicationCommandEdit"); n != 0 {
+		t.Errorf("edited unchanged commands %d times", n)
+	}
+
+	if err := build("Ping the bot, again").Register(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Calls("ApplicationCommandEdit"); n != 1 {
+		t.Errorf("edited commands %d times, want 1", n)
+	}
+
+	// moving the commands to a guild removes them globally
+	g := command.NewGroup().InGuild("guild")
+	g.Command("ping").Description("Ping the bot").Handler(reply("pong")).Build()
+	if err := g.Register(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Commands("")); n != 0 {
+		t.Errorf("%d global commands are left, want 0", n)
+	}
+	if n := len(s.Commands("guild")); n != 1 {
+		t.Errorf("registered %d guild commands, want 1", n)
+	}
+}
+
+func TestHandlerDispatchesSubcommands(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+
+	g := command.NewGroup()
+	cfg := g.Command("config").Description("Configure").Handler(reply("unreachable")).Build()
+	set := cfg.Subcommand("set").
+		Description("Set a value").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+			name, err := opts.String("name")
+			if err != nil {
+				return err
+			}
+			n, err := opts.IntOr("value", 1)
+			if err != nil {
+				return err
+			}
+			return say(s, i.Interaction, strings.Repeat(name, int(n)))
+		}).
+		Build()
+	set.Option("name").Description("The name").Type(discordgo.ApplicationCommandOptionString).Required().Build()
+	set.Option("value").Description("The value").Type(discordgo.ApplicationCommandOptionInteger).Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	i := discordtest.Command(u, "guild", "config",
+		discordtest.Subcommand("set", discordtest.String("name", "ab"), discordtest.Int("value", 3)))
+	g.Handler(s, i)
+	if got := content(t, s, i); got != "ababab" {
+		t.Errorf("got %q, want %q", got, "ababab")
+	}
+}
+
+func TestSubcommandGroups(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+
+	g := command.NewGroup()
+	synth := g.Command("synth").Description("Configure").Handler(reply("unreachable")).Build()
+	rules := synth.SubcommandGroup("rules").Description("Speech rules")
+	rules.Subcommand("add").Description("Add a rule").Handler(reply("added")).Build()
+	rules.Subcommand("list").Description("List the rules").Handler(reply("listed")).Build()
+	rules.Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	i := discordtest.Command(u, "guild", "synth", discordtest.Subcommand("rules", discordtest.Subcommand("list")))
+	g.Handler(s, i)
+	if got := content(t, s, i); got != "listed" {
+		t.Errorf("got %q, want %q", got, "listed")
+	}
+
+	// a group without subcommands can't be registered with Discord, so it is caught when it is built
+	defer func() {
+		if recover() == nil {
+			t.Error("an empty subcommand group was built")
+		}
+	}()
+	synth.SubcommandGroup("phrases").Description("Canned phrases").Build()
+}
+
+// suggest is an AutocompleteHandler that suggests what has been typed, followed by a fixed suffix.
+func suggest(suffix string) command.AutocompleteHandler {
+	return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options, typed string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+		return []*discordgo.ApplicationCommandOptionChoice{{Name: typed + suffix, Value: typed + suffix}}, nil
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+
+	g := command.NewGroup()
+	g.Command("say").Description("Say something").Handler(reply("said")).Build().
+		Option("text").Description("What to say").Type(discordgo.ApplicationCommandOptionString).Autocomplete(suggest("!")).Build()
+	synth := g.Command("synth").Description("Configure").Handler(reply("unreachable")).Build()
+	rules := synth.SubcommandGroup("rules").Description("Speech rules")
+	rules.Subcommand("remove").Description("Remove a rule").Handler(reply("removed")).Build().
+		Option("rule").Description("The rule").Type(discordgo.ApplicationCommandOptionString).Autocomplete(suggest("?")).Build()
+	rules.Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		i    *discordgo.InteractionCreate
+		// want is the suggestion, or "" if there should be none.
+		want string
+	}{
+		{"top level", discordtest.Autocomplete(u, "guild", "say", discordtest.Focused(discordtest.String("text", "hi"))), "hi!"},
+		{
+			"in a subcommand group",
+			discordtest.Autocomplete(u, "guild", "synth",
+				discordtest.Subcommand("rules", discordtest.Subcommand("remove", discordtest.Focused(discordtest.String("rule", "pre"))))),
+			"pre?",
+		},
+		{"unknown option", discordtest.Autocomplete(u, "guild", "say", discordtest.Focused(discordtest.String("tone", "loud"))), ""},
+	} {
+		g.Handler(s, tc.i)
+		r := s.Response(tc.i.ID)
+		if r == nil || r.Type != discordgo.InteractionApplicationCommandAutocompleteResult {
+			t.Errorf("%s: got response %+v, want suggestions", tc.name, r)
+			continue
+		}
+		var got []string
+		for _, c := range r.Data.Choices {
+			got = append(got, c.Name)
+		}
+		if tc.want == "" && len(got) != 0 || tc.want != "" && (len(got) != 1 || got[0] != tc.want) {
+			t.Errorf("%s: got suggestions %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOptionLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		typ   discordgo.ApplicationCommandOptionType
+		build func(*command.OptionBuilder) *command.OptionBuilder
+		// check checks the registered option, or is nil if the option should not build.
+		check func(*discordgo.ApplicationCommandOption) bool
+	}{
+		{
+			"value range", discordgo.ApplicationCommandOptionInteger,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MinValue(-5).MaxValue(-1) },
+			func(o *discordgo.ApplicationCommandOption) bool { return *o.MinValue == -5 && o.MaxValue == -1 },
+		},
+		{
+			"zero minimum value", discordgo.ApplicationCommandOptionNumber,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MinValue(0) },
+			func(o *discordgo.ApplicationCommandOption) bool { return o.MinValue != nil && *o.MinValue == 0 },
+		},
+		{
+			"length range", discordgo.ApplicationCommandOptionString,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MinLength(0).MaxLength(1) },
+			func(o *discordgo.ApplicationCommandOption) bool { return *o.MinLength == 0 && o.MaxLength == 1 },
+		},
+		{
+			"zero maximum value", discordgo.ApplicationCommandOptionInteger,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MaxValue(0) }, nil,
+		},
+		{
+			"zero maximum length", discordgo.ApplicationCommandOptionString,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MaxLength(0) }, nil,
+		},
+		{
+			"maximum value on a string", discordgo.ApplicationCommandOptionString,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MaxValue(-1) }, nil,
+		},
+		{
+			"maximum length on an integer", discordgo.ApplicationCommandOptionInteger,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MaxLength(10) }, nil,
+		},
+		{
+			"minimum above maximum", discordgo.ApplicationCommandOptionInteger,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MinValue(2).MaxValue(1) }, nil,
+		},
+		{
+			"minimum length above maximum", discordgo.ApplicationCommandOptionString,
+			func(b *command.OptionBuilder) *command.OptionBuilder { return b.MinLength(2).MaxLength(1) }, nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := command.NewGroup()
+			cmd := g.Command("pick").Description("Pick something").Handler(reply("picked")).Build()
+			opt := tc.build(cmd.Option("value").Description("The value").Type(tc.typ))
+
+			if tc.check == nil {
+				defer func() {
+					if recover() == nil {
+						t.Error("the option was built")
+					}
+				}()
+				opt.Build()
+				return
+			}
+
+			opt.Build()
+			s := discordtest.New("bot")
+			if err := g.Register(t.Context(), s); err != nil {
+				t.Fatal(err)
+			}
+			if o := s.Commands("")[0].Options[0]; !tc.check(o) {
+				t.Errorf("registered %+v", o)
+			}
+		})
+	}
+}
+
+func TestHandlerDeniesUnauthorizedUsers(t *testing.T) {
+	s := discordtest.New("bot")
+	owner := discordtest.NewUser("owner")
+	other := discordtest.NewUser("other")
+
+	g := command.NewGroup().ForOwner(owner.ID)
+	g.Command("secret").
+		Description("Owner only").
+		Handler(reply("the secret")).
+		Policy(authorizer.AllowIf("self", authorizer.Self{})).
+		Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	i := discordtest.Command(owner, "", "secret")
+	g.Handler(s, i)
+	if got := content(t, s, i); got != "the secret" {
+		t.Errorf("owner got %q", got)
+	}
+
+	i = discordtest.Command(other, "", "secret")
+	g.Handler(s, i)
+	if got := content(t, s, i); got == "the secret" {
+		t.Error("other user was allowed to use the command")
+	}
+}
+
+func TestHandlerReportsErrorsAndPanics(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+
+	g := command.NewGroup()
+	g.Command("panic").
+		Description("Panics").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+			panic("oops")
+		}).
+		Build()
+	g.Command("fail").
+		Description("Responds and then fails").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+			_ = say(s, i.Interaction, "it broke")
+			return context.DeadlineExceeded
+		}).
+		Build()
+	g.Command("slow").
+		Description("Defers and then fails").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+			_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			})
+			return context.DeadlineExceeded
+		}).
+		Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	i := discordtest.Command(u, "guild", "panic")
+	g.Handler(s, i)
+	if got := content(t, s, i); !strings.Contains(got, "Error ID") {
+		t.Errorf("panic got %q, want an error ID", got)
+	}
+
+	// the handler's own response is kept
+	i = discordtest.Command(u, "guild", "fail")
+	g.Handler(s, i)
+	if got := content(t, s, i); got != "it broke" {
+		t.Errorf("failure got %q", got)
+	}
+
+	// a deferred response is replaced, rather than left thinking
+	i = discordtest.Command(u, "guild", "slow")
+	g.Handler(s, i)
+	if edits := s.ResponseEdits(i.ID); len(edits) != 1 || !strings.Contains(*edits[0].Content, "Error ID") {
+		t.Errorf("deferred failure was edited to %v, want an error ID", edits)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+	other := discordtest.NewUser("other")
+
+	g := command.NewGroup()
+	g.Command("slow").
+		Description("Slow").
+		Handler(reply("done")).
+		Cooldown(time.Hour, command.PerUser).
+		Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		u    *discordgo.User
+		want string
+	}{
+		{u, "done"},
+		{u, "Slow down!"},
+		{other, "done"},
+	} {
+		i := discordtest.Command(tc.u, "guild", "slow")
+		g.Handler(s, i)
+		if got := content(t, s, i); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("%s got %q, want %q", tc.u.Username, got, tc.want)
+		}
+	}
+}
+
+func TestCooldownOnlyTakenBySuccess(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+
+	// the command fails, then rejects its input, then works
+	results := []string{"fail", "reject", "done"}
+	g := command.NewGroup()
+	g.Command("flaky").
+		Description("Flaky").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, opts *command.Options) error {
+			result := results[0]
+			results = results[1:]
+			switch result {
+			case "fail":
+				return errors.New("it broke")
+			case "reject":
+				command.CancelCooldown(ctx)
+			}
+			return say(s, i.Interaction, result)
+		}).
+		SharedCooldown("shared", time.Hour, command.PerGroup).
+		Build()
+	g.Component("also-flaky").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+			return say(s, i.Interaction, "done")
+		}).
+		SharedCooldown("shared", time.Hour, command.PerGroup).
+		Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Error ID", "reject", "done"} {
+		i := discordtest.Command(u, "guild", "flaky")
+		g.Handler(s, i)
+		if got := content(t, s, i); !strings.Contains(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	// the component shares the cooldown that the command finally took
+	i := discordtest.Button(u, "guild", "also-flaky")
+	g.Handler(s, i)
+	if got := content(t, s, i); !strings.HasPrefix(got, "Slow down!") {
+		t.Errorf("component got %q, want it cooling down", got)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	s := discordtest.New("bot")
+	u := discordtest.NewUser("user")
+
+	g := command.NewGroup()
+	g.Component("add").
+		Handler(func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate, id command.CustomID) error {
+			a, err := id.Int(0)
+			if err != nil {
+				return err
+			}
+			b, err := id.Int(1)
+			if err != nil {
+				return err
+			}
+			return say(s, i.Interaction, strconv.FormatInt(a+b, 10))
+		}).
+		Build()
+	if err := g.Register(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	i := discordtest.Button(u, "guild", command.NewCustomID("add", 2, 3).String())
+	g.Handler(s, i)
+	if got := content(t, s, i); got != "5" {
+		t.Errorf("got %q, want %q", got, "5")
+	}
+
+	i = discordtest.Button(u, "guild", command.NewCustomID("subtract", 2, 3).String())
+	g.Handler(s, i)
+	if got := content(t, s, i); !strings.Contains(got, "Error ID") {
+		t.Errorf("unknown component got %q, want an error", got)
+	}
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// Localizer provides the names and descriptions of commands, subcommands, and options in other languages. They are
+// looked up by a key for the command, as key+".name" and key+".description".
+type Localizer interface {
+	// Default gets the text for a key in the default language, or "" if there is none.
+	Default(key string) string
+	// Localizations gets the text for a key in every other language that has it.
+	Localizations(key string) map[discordgo.Locale]string
+}
+
+// localize looks up the description in the default language, and the name and description localizations, for key.
+// Empty localizations are returned as nil.
+func localize(l Localizer, key string) (string, map[discordgo.Locale]string, map[discordgo.Locale]string) {
+	names := l.Localizations(key + ".name")
+	if len(names) == 0 {
+		names = nil
+	}
+	descriptions := l.Localizations(key + ".description")
+	if len(descriptions) == 0 {
+		descriptions = nil
+	}
+	return l.Default(key + ".description"), names, descriptions
+}
+
+// validateLocalizations panics if the localized names or descriptions of a slash command, subcommand, subcommand group,
+// or option are not allowed by Discord.
+func validateLocalizations(kind, name string, names, descriptions map[discordgo.Locale]string) {
+	for l, n := range names {
+		if !nameRegexp.MatchString(n) {
+			log.Panic().Str("name", name).Str("locale", string(l)).Str("localized", n).
+				Msg(kind + " name must be 1-32 lowercase letters, numbers, dashes, or underscores")
+		}
+	}
+	for l, d := range descriptions {
+		if d == "" || utf8.RuneCountInString(d) > maxDescriptionLength {
+			log.Panic().Str("name", name).Str("locale", string(l)).
+				Msgf("%s description must be 1-%d characters", kind, maxDescriptionLength)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/discord"
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+// InteractionHandler handles any interaction that a Group handles.
+type InteractionHandler func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) error
+
+// Middleware wraps the handling of interactions. It can do something before or after calling next, or not call it at
+// all, in which case it should respond to the interaction itself.
+type Middleware func(next InteractionHandler) InteractionHandler
+
+// Use adds middleware that every interaction the Group handles is passed through. The first middleware added is the
+// outermost.
+func (g *Group) Use(mw ...Middleware) *Group {
+	g.middleware = append(g.middleware, mw...)
+	return g
+}
+
+// Timing is middleware that logs how long each interaction took to handle.
+func Timing() Middleware {
+	return func(next InteractionHandler) InteractionHandler {
+		return func(ctx context.Context, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) error {
+			start := time.Now()
+			err := next(ctx, s, u, i)
+			log.Ctx(ctx).Debug().
+				Str("type", i.Type.String()).
+				Dur("duration", time.Since(start)).
+				Bool("failed", err != nil).
+				Msg("Handled interaction")
+			return err
+		}
+	}
+}
+
+// recovered calls h, turning a panic into an error so that one bad handler doesn't take down every Synth.
+func recovered(ctx context.Context, h InteractionHandler, s discord.Session, u *discordgo.User, i *discordgo.InteractionCreate) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Ctx(ctx).Error().Str("stack", string(debug.Stack())).Msg("Recovered from panic in handler")
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, s, u, i)
+}
+
+// fail logs an error from handling an interaction, and tells the user something went wrong if the handler hadn't
+// responded yet, or had only deferred its response. The user is given an ID they can report, which is in the log.
+func fail(ctx context.Context, s *trackedSession, i *discordgo.InteractionCreate, err error) {
+	id := newErrorID()
+	log.Ctx(ctx).Err(err).Str("error_id", id).Msg("Error handling interaction")
+
+	// there's nothing to show the user for a failed autocomplete, they just don't get suggestions
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return
+	}
+
+	msg := i18n.T(i.Locale, "command.failed", id)
+	if s.thinking() {
+		// otherwise the user would be left looking at "thinking..." forever
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+		if err != nil {
+			log.Ctx(ctx).Err(err).Str("error_id", id).Msg("Error responding with error")
+		}
+		return
+	}
+
+	err = respond(s, i.Interaction, msg)
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil &&
+		restErr.Message.Code == discordgo.ErrCodeInteractionHasAlreadyBeenAcknowledged {
+		// the handler already told the user
+		return
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Str("error_id", id).Msg("Error responding with error")
+	}
+}
+
+// trackedSession keeps track of whether a handler left its response deferred, so that fail can replace it.
+type trackedSession struct {
+	discord.Session
+
+	mu sync.Mutex
+	// deferred is set while the response is a deferred message that hasn't been edited yet.
+	deferred bool
+}
+
+func (t *trackedSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	err := t.Session.InteractionRespond(interaction, resp, options...)
+	if err == nil {
+		t.mu.Lock()
+		t.deferred = resp.Type == discordgo.InteractionResponseDeferredChannelMessageWithSource
+		t.mu.Unlock()
+	}
+	return err
+}
+
+func (t *trackedSession) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	m, err := t.Session.InteractionResponseEdit(interaction, newresp, options...)
+	if err == nil {
+		t.mu.Lock()
+		t.deferred = false
+		t.mu.Unlock()
+	}
+	return m, err
+}
+
+// thinking reports whether the user is still being shown that the response is on its way.
+func (t *trackedSession) thinking() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.deferred
+}
+
+// newErrorID makes a short, random ID to correlate an error a user sees with the log.
+func newErrorID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
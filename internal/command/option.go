@@ -6,12 +6,17 @@ import (
 )
 
 type OptionBuilder struct {
-	opt *discordgo.ApplicationCommandOption
-	cmd optionable
+	opt          *discordgo.ApplicationCommandOption
+	cmd          optionable
+	autocomplete AutocompleteHandler
+	// maxValue and maxLength are kept here until the option is built, as the option can't tell an explicit 0 from
+	// one that isn't set.
+	maxValue  *float64
+	maxLength *int
 }
 
 type optionable interface {
-	addOption(*discordgo.ApplicationCommandOption)
+	addOption(*discordgo.ApplicationCommandOption, AutocompleteHandler)
 }
 
 func newOptionBuilder(cmd optionable, name string) *OptionBuilder {
@@ -24,17 +29,58 @@ func newOptionBuilder(cmd optionable, name string) *OptionBuilder {
 }
 
 func (b *OptionBuilder) Build() {
-	if b.opt.Name == "" {
-		log.Panic().Msg("Option name is required")
-	}
-	if b.opt.Description == "" {
-		log.Panic().Msg("Option description is required")
-	}
+	validate("Option", b.opt.Name, b.opt.Description)
+	validateLocalizations("Option", b.opt.Name, b.opt.NameLocalizations, b.opt.DescriptionLocalizations)
 	if b.opt.Type == 0 {
 		log.Panic().Msg("Option type is required")
 	}
+	numeric := b.opt.Type == discordgo.ApplicationCommandOptionInteger || b.opt.Type == discordgo.ApplicationCommandOptionNumber
+	str := b.opt.Type == discordgo.ApplicationCommandOptionString
+	if b.autocomplete != nil && !numeric && !str {
+		log.Panic().Str("option", b.opt.Name).Msg("Only string, integer, and number options can be autocompleted")
+	}
+	if len(b.opt.Choices) > 0 {
+		if !numeric && !str {
+			log.Panic().Str("option", b.opt.Name).Msg("Only string, integer, and number options can have choices")
+		} else if b.autocomplete != nil {
+			log.Panic().Str("option", b.opt.Name).Msg("Options cannot have both choices and autocomplete")
+		} else if len(b.opt.Choices) > maxChoices {
+			log.Panic().Str("option", b.opt.Name).Msgf("Options cannot have more than %d choices", maxChoices)
+		}
+	}
+	if (b.opt.MinValue != nil || b.maxValue != nil) && !numeric {
+		log.Panic().Str("option", b.opt.Name).Msg("Only integer and number options can have a minimum or maximum value")
+	}
+	if b.opt.MinValue != nil && b.maxValue != nil && *b.opt.MinValue > *b.maxValue {
+		log.Panic().Str("option", b.opt.Name).Msg("Option minimum value cannot be more than its maximum value")
+	}
+	if b.maxValue != nil {
+		if *b.maxValue == 0 {
+			// discordgo leaves out a maximum of 0 when registering the option, so it would silently have no maximum
+			log.Panic().Str("option", b.opt.Name).Msg("Option maximum value cannot be 0")
+		}
+		b.opt.MaxValue = *b.maxValue
+	}
+	if (b.opt.MinLength != nil || b.maxLength != nil) && !str {
+		log.Panic().Str("option", b.opt.Name).Msg("Only string options can have a minimum or maximum length")
+	}
+	if b.opt.MinLength != nil && (*b.opt.MinLength < 0 || *b.opt.MinLength > maxOptionLength) {
+		log.Panic().Str("option", b.opt.Name).Msgf("Option minimum length must be between 0 and %d", maxOptionLength)
+	}
+	if b.maxLength != nil {
+		if *b.maxLength < 1 || *b.maxLength > maxOptionLength {
+			log.Panic().Str("option", b.opt.Name).Msgf("Option maximum length must be between 1 and %d", maxOptionLength)
+		}
+		if b.opt.MinLength != nil && *b.opt.MinLength > *b.maxLength {
+			log.Panic().Str("option", b.opt.Name).Msg("Option minimum length cannot be more than its maximum length")
+		}
+		b.opt.MaxLength = *b.maxLength
+	}
+	if len(b.opt.ChannelTypes) > 0 && b.opt.Type != discordgo.ApplicationCommandOptionChannel {
+		log.Panic().Str("option", b.opt.Name).Msg("Only channel options can be limited to channel types")
+	}
 
-	b.cmd.addOption(b.opt)
+	b.cmd.addOption(b.opt, b.autocomplete)
 }
 
 func (b *OptionBuilder) Description(d string) *OptionBuilder {
@@ -51,3 +97,82 @@ func (b *OptionBuilder) Required() *OptionBuilder {
 	b.opt.Required = true
 	return b
 }
+
+// Autocomplete enables autocompletion for the option, with suggestions provided by h. Only string, integer, and number
+// options can be autocompleted.
+func (b *OptionBuilder) Autocomplete(h AutocompleteHandler) *OptionBuilder {
+	b.opt.Autocomplete = true
+	b.autocomplete = h
+	return b
+}
+
+// Choice adds a choice for the option, which limits the option to only the values of its choices. value must be a
+// string, integer, or number, matching the type of the option.
+func (b *OptionBuilder) Choice(name string, value any) *OptionBuilder {
+	b.opt.Choices = append(b.opt.Choices, &discordgo.ApplicationCommandOptionChoice{
+		Name:  name,
+		Value: value,
+	})
+	return b
+}
+
+// MinValue sets the smallest value an integer or number option can have.
+func (b *OptionBuilder) MinValue(v float64) *OptionBuilder {
+	b.opt.MinValue = &v
+	return b
+}
+
+// MaxValue sets the largest value an integer or number option can have. It cannot be 0, as discordgo cannot register
+// that.
+func (b *OptionBuilder) MaxValue(v float64) *OptionBuilder {
+	b.maxValue = &v
+	return b
+}
+
+// MinLength sets the shortest a string option can be, from 0 to 6000 characters.
+func (b *OptionBuilder) MinLength(l int) *OptionBuilder {
+	b.opt.MinLength = &l
+	return b
+}
+
+// MaxLength sets the longest a string option can be, from 1 to 6000 characters.
+func (b *OptionBuilder) MaxLength(l int) *OptionBuilder {
+	b.maxLength = &l
+	return b
+}
+
+// ChannelTypes limits a channel option to the given types of channel.
+func (b *OptionBuilder) ChannelTypes(t ...discordgo.ChannelType) *OptionBuilder {
+	b.opt.ChannelTypes = t
+	return b
+}
+
+// Localize sets the description of the option from key+".description" in l, and the name and description in other
+// languages from key+".name" and key+".description".
+func (b *OptionBuilder) Localize(l Localizer, key string) *OptionBuilder {
+	d, names, descriptions := localize(l, key)
+	if d != "" {
+		b.opt.Description = d
+	}
+	b.opt.NameLocalizations = names
+	b.opt.DescriptionLocalizations = descriptions
+	return b
+}
+
+// NameLocalization sets the name of the option in the given locale.
+func (b *OptionBuilder) NameLocalization(l discordgo.Locale, name string) *OptionBuilder {
+	if b.opt.NameLocalizations == nil {
+		b.opt.NameLocalizations = make(map[discordgo.Locale]string)
+	}
+	b.opt.NameLocalizations[l] = name
+	return b
+}
+
+// DescriptionLocalization sets the description of the option in the given locale.
+func (b *OptionBuilder) DescriptionLocalization(l discordgo.Locale, d string) *OptionBuilder {
+	if b.opt.DescriptionLocalizations == nil {
+		b.opt.DescriptionLocalizations = make(map[discordgo.Locale]string)
+	}
+	b.opt.DescriptionLocalizations[l] = d
+	return b
+}
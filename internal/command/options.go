@@ -0,0 +1,190 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var ErrMissingOption = errors.New("missing option")
+var ErrWrongOptionType = errors.New("wrong option type")
+
+var ErrMissingTarget = errors.New("missing target")
+
+// Options are the options a command was used with. Only the options of the subcommand that was used are included.
+// Context menu commands have no options, only the user or message they were used on.
+type Options struct {
+	opts     map[string]*discordgo.ApplicationCommandInteractionDataOption
+	resolved *discordgo.ApplicationCommandInteractionDataResolved
+	targetID string
+}
+
+// newOptions gets the options of the command or subcommand that was used in an interaction.
+func newOptions(i *discordgo.InteractionCreate) *Options {
+	data := i.ApplicationCommandData()
+	o := &Options{
+		opts:     make(map[string]*discordgo.ApplicationCommandInteractionDataOption),
+		resolved: data.Resolved,
+		targetID: data.TargetID,
+	}
+
+	for _, opt := range leafOptions(data.Options) {
+		o.opts[opt.Name] = opt
+	}
+	return o
+}
+
+// leafOptions gets the options of the subcommand that was used, if any, from the options of the command.
+func leafOptions(options []*discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandInteractionDataOption {
+	for len(options) > 0 && (options[0].Type == discordgo.ApplicationCommandOptionSubCommand ||
+		options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup) {
+		options = options[0].Options
+	}
+	return options
+}
+
+// Has returns if the option was given.
+func (o *Options) Has(name string) bool {
+	_, ok := o.opts[name]
+	return ok
+}
+
+// get gets an option, ensuring it was given and is of the expected type.
+func (o *Options) get(name string, t discordgo.ApplicationCommandOptionType) (*discordgo.ApplicationCommandInteractionDataOption, error) {
+	opt, ok := o.opts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissingOption, name)
+	} else if opt.Type != t {
+		return nil, fmt.Errorf("%w: %s is %s, not %s", ErrWrongOptionType, name, opt.Type, t)
+	}
+	return opt, nil
+}
+
+func (o *Options) String(name string) (string, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionString)
+	if err != nil {
+		return "", err
+	}
+	return opt.StringValue(), nil
+}
+
+func (o *Options) Int(name string) (int64, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionInteger)
+	if err != nil {
+		return 0, err
+	}
+	return opt.IntValue(), nil
+}
+
+func (o *Options) Float(name string) (float64, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionNumber)
+	if err != nil {
+		return 0, err
+	}
+	return opt.FloatValue(), nil
+}
+
+func (o *Options) Bool(name string) (bool, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionBoolean)
+	if err != nil {
+		return false, err
+	}
+	return opt.BoolValue(), nil
+}
+
+// User gets a user option. The user is fully populated if Discord included it in the interaction, otherwise only the ID
+// is set.
+func (o *Options) User(name string) (*discordgo.User, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionUser)
+	if err != nil {
+		return nil, err
+	}
+	u := opt.UserValue(nil)
+	if o.resolved != nil {
+		if resolved, ok := o.resolved.Users[u.ID]; ok {
+			return resolved, nil
+		}
+	}
+	return u, nil
+}
+
+// Role gets a role option. The role is fully populated if Discord included it in the interaction, otherwise only the ID
+// is set.
+func (o *Options) Role(name string) (*discordgo.Role, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionRole)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := opt.Value.(string)
+	if o.resolved != nil {
+		if resolved, ok := o.resolved.Roles[id]; ok {
+			return resolved, nil
+		}
+	}
+	return &discordgo.Role{ID: id}, nil
+}
+
+// Channel gets a channel option. The channel is fully populated if Discord included it in the interaction, otherwise
+// only the ID is set.
+func (o *Options) Channel(name string) (*discordgo.Channel, error) {
+	opt, err := o.get(name, discordgo.ApplicationCommandOptionChannel)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := opt.Value.(string)
+	if o.resolved != nil {
+		if resolved, ok := o.resolved.Channels[id]; ok {
+			return resolved, nil
+		}
+	}
+	return &discordgo.Channel{ID: id}, nil
+}
+
+// StringOr gets a string option, or def if it was not given.
+func (o *Options) StringOr(name, def string) (string, error) {
+	if !o.Has(name) {
+		return def, nil
+	}
+	return o.String(name)
+}
+
+// IntOr gets an integer option, or def if it was not given.
+func (o *Options) IntOr(name string, def int64) (int64, error) {
+	if !o.Has(name) {
+		return def, nil
+	}
+	return o.Int(name)
+}
+
+// BoolOr gets a boolean option, or def if it was not given.
+func (o *Options) BoolOr(name string, def bool) (bool, error) {
+	if !o.Has(name) {
+		return def, nil
+	}
+	return o.Bool(name)
+}
+
+// TargetUser gets the user a user command was used on.
+func (o *Options) TargetUser() (*discordgo.User, error) {
+	if o.targetID == "" || o.resolved == nil {
+		return nil, ErrMissingTarget
+	}
+	u, ok := o.resolved.Users[o.targetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: user %s", ErrMissingTarget, o.targetID)
+	}
+	return u, nil
+}
+
+// TargetMessage gets the message a message command was used on.
+func (o *Options) TargetMessage() (*discordgo.Message, error) {
+	if o.targetID == "" || o.resolved == nil {
+		return nil, ErrMissingTarget
+	}
+	m, ok := o.resolved.Messages[o.targetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: message %s", ErrMissingTarget, o.targetID)
+	}
+	return m, nil
+}
@@ -1,29 +1,52 @@
 package command
 
 import (
+	"time"
+
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
 )
 
 type Subcommand struct {
-	subcmd  *discordgo.ApplicationCommandOption
-	handler Handler
+	subcmd        *discordgo.ApplicationCommandOption
+	handler       Handler
+	autocompletes map[string]AutocompleteHandler
+	// name is the full name of the subcommand, such as "/cmd group sub".
+	name string
+	// policy is the policy that applies to the subcommand, whether it was set on the subcommand or inherited.
+	policy authorizer.Policy
 }
 
 type SubcommandBuilder struct {
-	opt     *discordgo.ApplicationCommandOption
-	cmd     subcommandable
-	handler Handler
+	opt      *discordgo.ApplicationCommandOption
+	cmd      subcommandable
+	handler  Handler
+	policy   authorizer.Policy
+	cooldown *cooldown
 }
 
+// subcommandable is something that can have subcommands: a Command or a SubcommandGroup.
 type subcommandable interface {
-	optionable
-
 	addSubcommand(*Subcommand)
+	// path is the full name of the command or subcommand group, such as "/cmd group".
+	path() string
+	// inheritedPolicy is the policy for subcommands that do not set their own.
+	inheritedPolicy() authorizer.Policy
+	// inheritedCooldown is the cooldown for subcommands that do not set their own.
+	inheritedCooldown() *cooldown
+	group() *Group
 }
 
-func (s *Subcommand) addOption(opt *discordgo.ApplicationCommandOption) {
-	s.subcmd.Options = append(s.subcmd.Options, opt)
+func (s *Subcommand) addOption(opt *discordgo.ApplicationCommandOption, ac AutocompleteHandler) {
+	s.subcmd.Options = appendOption(s.name, s.subcmd.Options, opt)
+	if ac != nil {
+		if s.autocompletes == nil {
+			s.autocompletes = make(map[string]AutocompleteHandler)
+		}
+		s.autocompletes[opt.Name] = ac
+	}
 }
 
 func (s *Subcommand) Option(name string) *OptionBuilder {
@@ -41,12 +64,8 @@ func newSubcommandBuilder(cmd subcommandable, name string) *SubcommandBuilder {
 }
 
 func (b *SubcommandBuilder) Build() *Subcommand {
-	if b.opt.Name == "" {
-		log.Panic().Msg("Subcommand name is required")
-	}
-	if b.opt.Description == "" {
-		log.Panic().Msg("Subcommand description is required")
-	}
+	validate("Subcommand", b.opt.Name, b.opt.Description)
+	validateLocalizations("Subcommand", b.opt.Name, b.opt.NameLocalizations, b.opt.DescriptionLocalizations)
 	if b.opt.Type == 0 {
 		log.Panic().Msg("Subcommand type is required")
 	}
@@ -54,9 +73,21 @@ func (b *SubcommandBuilder) Build() *Subcommand {
 		log.Panic().Msg("Subcommand handler is required")
 	}
 
+	p := b.policy
+	if p == nil {
+		p = b.cmd.inheritedPolicy()
+	}
+	cd := b.cooldown
+	if cd == nil {
+		cd = b.cmd.inheritedCooldown()
+	}
+	name := b.cmd.path() + " " + b.opt.Name
+	grp := b.cmd.group()
 	s := &Subcommand{
 		subcmd:  b.opt,
-		handler: b.handler,
+		handler: grp.authorize(name, p, grp.withCooldown(name, cd, b.handler)),
+		name:    name,
+		policy:  p,
 	}
 	b.cmd.addSubcommand(s)
 	return s
@@ -67,7 +98,39 @@ func (b *SubcommandBuilder) Description(d string) *SubcommandBuilder {
 	return b
 }
 
+// Localize sets the description of the subcommand from key+".description" in l, and the name and description in other
+// languages from key+".name" and key+".description".
+func (b *SubcommandBuilder) Localize(l Localizer, key string) *SubcommandBuilder {
+	d, names, descriptions := localize(l, key)
+	if d != "" {
+		b.opt.Description = d
+	}
+	b.opt.NameLocalizations = names
+	b.opt.DescriptionLocalizations = descriptions
+	return b
+}
+
 func (b *SubcommandBuilder) Handler(handler Handler) *SubcommandBuilder {
 	b.handler = handler
 	return b
 }
+
+// Policy sets the policy that decides who can use the subcommand, instead of the policy of its group or command.
+func (b *SubcommandBuilder) Policy(p authorizer.Policy) *SubcommandBuilder {
+	b.policy = p
+	return b
+}
+
+// Cooldown limits how often the subcommand can be used, instead of the cooldown of its command. scope decides who
+// shares the cooldown.
+func (b *SubcommandBuilder) Cooldown(d time.Duration, scope CooldownScope) *SubcommandBuilder {
+	b.cooldown = &cooldown{d: d, scope: scope}
+	return b
+}
+
+// SharedCooldown is like Cooldown, but the cooldown is shared with everything else that uses the same key, such as a
+// command and a component that do the same thing.
+func (b *SubcommandBuilder) SharedCooldown(key string, d time.Duration, scope CooldownScope) *SubcommandBuilder {
+	b.cooldown = &cooldown{d: d, scope: scope, key: key}
+	return b
+}
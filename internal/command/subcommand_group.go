@@ -0,0 +1,101 @@
+package command
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/authorizer"
+)
+
+// SubcommandGroup is a group of subcommands under a Command. Subcommand groups cannot be nested, and cannot have
+// options of their own.
+type SubcommandGroup struct {
+	opt     *discordgo.ApplicationCommandOption
+	cmd     *Command
+	subcmds []*Subcommand
+	policy  authorizer.Policy
+}
+
+// SubcommandGroupBuilder builds a SubcommandGroup. Its subcommands are built with Subcommand before the group itself is
+// built, as a group must have at least one.
+type SubcommandGroupBuilder struct {
+	// g is the group being built. It isn't added to the command until it is built.
+	g *SubcommandGroup
+}
+
+func newSubcommandGroupBuilder(cmd *Command, name string) *SubcommandGroupBuilder {
+	return &SubcommandGroupBuilder{
+		g: &SubcommandGroup{
+			opt: &discordgo.ApplicationCommandOption{
+				Name: name,
+				Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+			},
+			cmd: cmd,
+		},
+	}
+}
+
+func (b *SubcommandGroupBuilder) Build() *SubcommandGroup {
+	opt := b.g.opt
+	validate("Subcommand group", opt.Name, opt.Description)
+	validateLocalizations("Subcommand group", opt.Name, opt.NameLocalizations, opt.DescriptionLocalizations)
+	if len(b.g.subcmds) == 0 {
+		log.Panic().Str("group", b.g.path()).Msg("Subcommand group has no subcommands")
+	}
+
+	b.g.cmd.addSubcommandGroup(b.g)
+	return b.g
+}
+
+func (b *SubcommandGroupBuilder) Description(d string) *SubcommandGroupBuilder {
+	b.g.opt.Description = d
+	return b
+}
+
+// Localize sets the description of the subcommand group from key+".description" in l, and the name and description in
+// other languages from key+".name" and key+".description".
+func (b *SubcommandGroupBuilder) Localize(l Localizer, key string) *SubcommandGroupBuilder {
+	d, names, descriptions := localize(l, key)
+	if d != "" {
+		b.g.opt.Description = d
+	}
+	b.g.opt.NameLocalizations = names
+	b.g.opt.DescriptionLocalizations = descriptions
+	return b
+}
+
+// Policy sets the policy that decides who can use the subcommands in the group, unless they set their own, instead of
+// the command's policy. It must be set before the subcommands are built.
+func (b *SubcommandGroupBuilder) Policy(p authorizer.Policy) *SubcommandGroupBuilder {
+	b.g.policy = p
+	return b
+}
+
+// Subcommand starts building a subcommand in the group.
+func (b *SubcommandGroupBuilder) Subcommand(name string) *SubcommandBuilder {
+	return newSubcommandBuilder(b.g, name)
+}
+
+func (g *SubcommandGroup) addSubcommand(s *Subcommand) {
+	g.opt.Options = appendOption(g.path(), g.opt.Options, s.subcmd)
+	g.subcmds = append(g.subcmds, s)
+}
+
+func (g *SubcommandGroup) path() string {
+	return g.cmd.path() + " " + g.opt.Name
+}
+
+func (g *SubcommandGroup) inheritedPolicy() authorizer.Policy {
+	if g.policy != nil {
+		return g.policy
+	}
+	return g.cmd.policy
+}
+
+func (g *SubcommandGroup) inheritedCooldown() *cooldown {
+	return g.cmd.cooldown
+}
+
+func (g *SubcommandGroup) group() *Group {
+	return g.cmd.grp
+}
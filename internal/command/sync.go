@@ -0,0 +1,131 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/discord"
+)
+
+// syncCommands makes the commands registered with Discord in a scope match the given commands, only creating, editing, and
+// deleting the commands that changed. guildID is the guild to sync, or "" for the global commands.
+//
+// Many Synths restart at once when SynthOS does, so this avoids making any changes when nothing changed.
+func syncCommands(ctx context.Context, s discord.Session, guildID string, cmds []*discordgo.ApplicationCommand) error {
+	logger := log.Ctx(ctx).With().Str("guild_id", guildID).Logger()
+	appID := s.Me().ID
+
+	existing, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("getting registered commands: %w", err)
+	}
+	registered := make(map[commandKey]*discordgo.ApplicationCommand, len(existing))
+	for _, c := range existing {
+		registered[commandKey{c.Type, c.Name}] = c
+	}
+
+	var errs []error
+	var created, edited, unchanged int
+	for _, c := range cmds {
+		key := commandKey{c.Type, c.Name}
+		cur, ok := registered[key]
+		delete(registered, key)
+
+		if !ok {
+			logger.Debug().Str("command", c.Name).Msg("Creating command")
+			_, err = s.ApplicationCommandCreate(appID, guildID, c)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("creating command %s: %w", c.Name, err))
+			}
+			created++
+		} else if !commandsEqual(c, cur) {
+			logger.Debug().Str("command", c.Name).Msg("Editing command")
+			_, err = s.ApplicationCommandEdit(appID, guildID, cur.ID, c)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("editing command %s: %w", c.Name, err))
+			}
+			edited++
+		} else {
+			unchanged++
+		}
+	}
+
+	// anything left is no longer wanted
+	for _, c := range registered {
+		logger.Debug().Str("command", c.Name).Msg("Deleting command")
+		err = s.ApplicationCommandDelete(appID, guildID, c.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deleting command %s: %w", c.Name, err))
+		}
+	}
+
+	logger.Trace().
+		Int("created", created).
+		Int("edited", edited).
+		Int("deleted", len(registered)).
+		Int("unchanged", unchanged).
+		Msg("Synced commands")
+	return errors.Join(errs...)
+}
+
+// commandsEqual returns if a registered command is the same as the command we want. Only the fields we set are
+// compared, as Discord fills in the rest.
+func commandsEqual(want, got *discordgo.ApplicationCommand) bool {
+	w := syncedFields(want, got)
+	g := syncedFields(got, got)
+	wj, err := json.Marshal(w)
+	if err != nil {
+		return false
+	}
+	gj, err := json.Marshal(g)
+	if err != nil {
+		return false
+	}
+	return string(wj) == string(gj)
+}
+
+// syncedFields copies the fields of a command that are compared by commandsEqual. Fields that c leaves to Discord's
+// defaults are taken from registered.
+func syncedFields(c, registered *discordgo.ApplicationCommand) *discordgo.ApplicationCommand {
+	out := &discordgo.ApplicationCommand{
+		Type:                     c.Type,
+		Name:                     c.Name,
+		NameLocalizations:        nonEmpty(c.NameLocalizations),
+		DefaultMemberPermissions: c.DefaultMemberPermissions,
+		NSFW:                     c.NSFW,
+		Contexts:                 c.Contexts,
+		IntegrationTypes:         c.IntegrationTypes,
+		Description:              c.Description,
+		DescriptionLocalizations: nonEmpty(c.DescriptionLocalizations),
+		Options:                  c.Options,
+	}
+	if out.Type == 0 {
+		out.Type = discordgo.ChatApplicationCommand
+	}
+	if out.NSFW == nil {
+		out.NSFW = new(false)
+	}
+	if out.Contexts == nil {
+		out.Contexts = registered.Contexts
+	}
+	if out.IntegrationTypes == nil {
+		out.IntegrationTypes = registered.IntegrationTypes
+	}
+	if len(out.Options) == 0 {
+		out.Options = nil
+	}
+	return out
+}
+
+// nonEmpty returns nil for an empty map of localizations, as Discord does not distinguish them from no localizations.
+func nonEmpty(m *map[discordgo.Locale]string) *map[discordgo.Locale]string {
+	if m == nil || len(*m) == 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// Discord limits on commands.
+const (
+	maxOptions           = 25
+	maxDescriptionLength = 100
+	maxOptionLength      = 6000
+)
+
+// nameRegexp matches valid names of commands, subcommands, and options. Discord requires them to be lowercase where the
+// script has case.
+var nameRegexp = regexp.MustCompile(`^[-_\p{Ll}\p{Lm}\p{Lo}\p{N}]{1,32}$`)
+
+// validate panics if the name or description of a command, subcommand, subcommand group, or option is not allowed by
+// Discord.
+func validate(kind, name, description string) {
+	if name == "" {
+		log.Panic().Msg(kind + " name is required")
+	}
+	if !nameRegexp.MatchString(name) {
+		log.Panic().Str("name", name).Msg(kind + " name must be 1-32 lowercase letters, numbers, dashes, or underscores")
+	}
+	if description == "" {
+		log.Panic().Str("name", name).Msg(kind + " description is required")
+	}
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		log.Panic().Str("name", name).Msgf("%s description cannot be longer than %d characters", kind, maxDescriptionLength)
+	}
+}
+
+// contextMenuNameRegexp matches valid names of context menu commands, which are shown as they are, so they can have
+// spaces and capital letters.
+var contextMenuNameRegexp = regexp.MustCompile(`^[-_ \p{L}\p{M}\p{N}]{1,32}$`)
+
+// validateContextMenu panics if the name or description of a user or message command is not allowed by Discord.
+func validateContextMenu(name, description string) {
+	if !contextMenuNameRegexp.MatchString(name) {
+		log.Panic().Str("name", name).Msg("Context menu command name must be 1-32 letters, numbers, spaces, dashes, or underscores")
+	}
+	if description != "" {
+		log.Panic().Str("name", name).Msg("Context menu commands cannot have a description")
+	}
+}
+
+// appendOption adds an option, subcommand, or subcommand group to a list of them, enforcing Discord's rules for the
+// list. parent is the full name of what the list belongs to, for logging.
+func appendOption(parent string, options []*discordgo.ApplicationCommandOption, opt *discordgo.ApplicationCommandOption) []*discordgo.ApplicationCommandOption {
+	if len(options) >= maxOptions {
+		log.Panic().Str("parent", parent).Msgf("Cannot have more than %d options", maxOptions)
+	}
+	for _, o := range options {
+		if o.Name == opt.Name {
+			log.Panic().Str("parent", parent).Str("name", opt.Name).Msg("Duplicate option name")
+		}
+		if opt.Required && !o.Required && o.Type != discordgo.ApplicationCommandOptionSubCommand &&
+			o.Type != discordgo.ApplicationCommandOptionSubCommandGroup {
+			log.Panic().Str("parent", parent).Str("name", opt.Name).Msg("Required options must come before optional options")
+		}
+	}
+	return append(options, opt)
+}
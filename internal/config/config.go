@@ -20,9 +20,12 @@ const (
 )
 
 type SynthOS struct {
-	AdminID    string
-	LogLevel   zerolog.Level
-	Controller ControllerBot
+	AdminID  string
+	LogLevel zerolog.Level
+	// CommandGuildID registers the commands of Synths in this guild only, instead of globally. Guild commands are
+	// updated immediately, so this is useful when developing.
+	CommandGuildID string
+	Controller     ControllerBot
 }
 
 type ControllerBot struct {
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditAction is the type of action recorded in an AuditEntry.
+type AuditAction string
+
+const (
+	AuditGrantHandler  AuditAction = "grant_handler"
+	AuditRevokeHandler AuditAction = "revoke_handler"
+	// AuditHandlerCommand is recorded when a handler uses a command on a Synth they do not own.
+	AuditHandlerCommand AuditAction = "handler_command"
+	// AuditModeratorCommand is recorded when a guild moderator uses a command on a Synth they do not own.
+	AuditModeratorCommand AuditAction = "moderator_command"
+	// AuditAdminCommand is recorded when the SynthOS admin uses a command on a Synth they do not own.
+	AuditAdminCommand AuditAction = "admin_command"
+)
+
+// AuditEntry records an action taken on a Synth.
+type AuditEntry struct {
+	ID      uint64 `gorm:"primary_key;auto_increment"`
+	SynthID uint64 `gorm:"not null;index"`
+	// ActorID is the Discord user that took the action.
+	ActorID string      `gorm:"not null"`
+	Action  AuditAction `gorm:"not null"`
+	// TargetID is the Discord user the action was taken on, if any.
+	TargetID string `gorm:"not null;default:''"`
+	// GuildID is the guild the action was taken in, if any.
+	GuildID string `gorm:"not null;default:''"`
+	Detail  string `gorm:"not null;default:''"`
+
+	CreatedAt time.Time
+}
+
+func insertAuditEntry(ctx context.Context, g *gorm.DB, e *AuditEntry) error {
+	err := gorm.G[AuditEntry](g).Create(ctx, e)
+	if err != nil {
+		return fmt.Errorf("saving AuditEntry: %w", err)
+	}
+	return nil
+}
+
+// InsertAuditEntry records an action taken on a Synth.
+func (db *DB) InsertAuditEntry(ctx context.Context, e *AuditEntry) error {
+	return insertAuditEntry(ctx, db.g, e)
+}
+
+// GetAuditEntries gets the most recent audit entries for the given Synth, newest first.
+func (db *DB) GetAuditEntries(ctx context.Context, synthID uint64, limit int) ([]AuditEntry, error) {
+	entries, err := gorm.G[AuditEntry](db.g).Where("synth_id = ?", synthID).Order("id DESC").Limit(limit).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AuditEntries: %w", err)
+	}
+	return entries, nil
+}
+
+// Audit records an action taken on this Synth.
+func (s *Synth) Audit(ctx context.Context, e *AuditEntry) error {
+	e.SynthID = s.ID
+	return s.db.InsertAuditEntry(ctx, e)
+}
+
+// AuditEntries gets the most recent audit entries for this Synth, newest first.
+func (s *Synth) AuditEntries(ctx context.Context, limit int) ([]AuditEntry, error) {
+	return s.db.GetAuditEntries(ctx, s.ID, limit)
+}
@@ -50,6 +50,14 @@ func (db *DB) migrate() error {
 	log.Trace().Msg("Migrating database...")
 
 	var err error
-	err = db.g.AutoMigrate(&Synth{})
+	err = db.g.AutoMigrate(
+		&Synth{},
+		&GuildSettings{},
+		&Energy{},
+		&SpeechRule{},
+		&Handler{},
+		&AuditEntry{},
+		&Phrase{},
+	)
 	return err
 }
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Energy tracks how much energy a Synth has in a particular guild. The limits are configured in GuildSettings.
+type Energy struct {
+	SynthID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	GuildID string `gorm:"primaryKey"`
+	// Current is how much energy the Synth had as of RegenAt.
+	Current float64 `gorm:"not null;default:0"`
+	RegenAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	db *DB
+}
+
+// GetEnergy gets the Energy for the given Synth in the given guild. If there is no Energy stored yet, a new, empty one
+// is returned; it is not persisted until Save is called.
+func (db *DB) GetEnergy(ctx context.Context, synthID uint64, guildID string) (*Energy, error) {
+	t, err := gorm.G[Energy](db.g).Where("synth_id = ? AND guild_id = ?", synthID, guildID).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading Energy: %w", err)
+	} else if len(t) == 0 {
+		return &Energy{
+			SynthID: synthID,
+			GuildID: guildID,
+			RegenAt: time.Now(),
+			db:      db,
+		}, nil
+	}
+
+	e := &t[0]
+	e.db = db
+	return e, nil
+}
+
+// Energy gets the Energy for this Synth in the given guild.
+func (s *Synth) Energy(ctx context.Context, guildID string) (*Energy, error) {
+	return s.db.GetEnergy(ctx, s.ID, guildID)
+}
+
+func (e *Energy) Save(ctx context.Context) error {
+	return gorm.G[Energy](e.db.g, clause.OnConflict{UpdateAll: true}).Create(ctx, e)
+}
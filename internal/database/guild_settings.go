@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GuildSettings are the settings for a Synth in a particular guild.
+type GuildSettings struct {
+	SynthID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	GuildID string `gorm:"primaryKey"`
+	// MaxEnergy is the most energy the Synth can have. Zero disables energy enforcement.
+	MaxEnergy int `gorm:"not null;default:0"`
+	// EnergyRegen is how much energy is regenerated per minute.
+	EnergyRegen int `gorm:"not null;default:0"`
+	// PronounRewrite enables rewriting first-person pronouns in what the Synth says.
+	PronounRewrite bool `gorm:"not null;default:false"`
+	// PronounReplacements are the replacements used for PronounRewrite, in the format used by
+	// speech.FormatPronounReplacements. Empty means to use the defaults.
+	PronounReplacements string `gorm:"not null;default:''"`
+	// Template wraps everything the Synth says, in the format used by speech.ParseTemplate. Empty means no template.
+	Template string `gorm:"not null;default:''"`
+	// HandlerRoleID is the role that can control the Synth in this guild, in addition to moderators. Empty means no role.
+	HandlerRoleID string `gorm:"not null;default:''"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	db *DB
+}
+
+// GetGuildSettings gets the settings for the given Synth in the given guild. If there are no settings stored yet, the
+// default settings are returned; they are not persisted until Save is called.
+func (db *DB) GetGuildSettings(ctx context.Context, synthID uint64, guildID string) (*GuildSettings, error) {
+	t, err := gorm.G[GuildSettings](db.g).Where("synth_id = ? AND guild_id = ?", synthID, guildID).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading GuildSettings: %w", err)
+	} else if len(t) == 0 {
+		return &GuildSettings{
+			SynthID: synthID,
+			GuildID: guildID,
+			db:      db,
+		}, nil
+	}
+
+	gs := &t[0]
+	gs.db = db
+	return gs, nil
+}
+
+// ListGuildSettings gets the settings for every guild the given Synth has had settings saved for.
+func (db *DB) ListGuildSettings(ctx context.Context, synthID uint64) ([]*GuildSettings, error) {
+	t, err := gorm.G[GuildSettings](db.g).Where("synth_id = ?", synthID).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading GuildSettings: %w", err)
+	}
+
+	ret := make([]*GuildSettings, 0, len(t))
+	for _, gs := range t {
+		gs.db = db
+		ret = append(ret, &gs)
+	}
+	return ret, nil
+}
+
+// DeleteGuildSettings deletes the settings for the given Synth in the given guild, reverting it to the defaults.
+func (db *DB) DeleteGuildSettings(ctx context.Context, synthID uint64, guildID string) error {
+	_, err := gorm.G[GuildSettings](db.g).Where("synth_id = ? AND guild_id = ?", synthID, guildID).Delete(ctx)
+	return err
+}
+
+// GuildSettings gets the settings for this Synth in the given guild.
+func (s *Synth) GuildSettings(ctx context.Context, guildID string) (*GuildSettings, error) {
+	return s.db.GetGuildSettings(ctx, s.ID, guildID)
+}
+
+// HandlerRole gets the ID of the role that can control this Synth in the given guild, or empty if there isn't one.
+func (s *Synth) HandlerRole(ctx context.Context, guildID string) (string, error) {
+	gs, err := s.GuildSettings(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	return gs.HandlerRoleID, nil
+}
+
+func (gs *GuildSettings) Save(ctx context.Context) error {
+	return gorm.G[GuildSettings](gs.db.g, clause.OnConflict{UpdateAll: true}).Create(ctx, gs)
+}
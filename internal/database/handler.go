@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Handler is a Discord user that the owner of a Synth has granted control over it.
+type Handler struct {
+	SynthID       uint64 `gorm:"primaryKey;autoIncrement:false"`
+	DiscordUserID string `gorm:"primaryKey"`
+	GrantedBy     string `gorm:"not null"`
+
+	CreatedAt time.Time
+}
+
+// GrantHandler grants a user control over the given Synth, recording it in the audit log. ErrAlreadyExists is returned
+// if the user is already a handler.
+func (db *DB) GrantHandler(ctx context.Context, synthID uint64, actorID, userID string) error {
+	return db.g.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := gorm.G[Handler](tx).Create(ctx, &Handler{
+			SynthID:       synthID,
+			DiscordUserID: userID,
+			GrantedBy:     actorID,
+		})
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("%w: %v", ErrAlreadyExists, err)
+		} else if err != nil {
+			return err
+		}
+
+		return insertAuditEntry(ctx, tx, &AuditEntry{
+			SynthID:  synthID,
+			ActorID:  actorID,
+			Action:   AuditGrantHandler,
+			TargetID: userID,
+		})
+	})
+}
+
+// RevokeHandler revokes a user's control over the given Synth, recording it in the audit log. ErrNotFound is returned
+// if the user is not a handler.
+func (db *DB) RevokeHandler(ctx context.Context, synthID uint64, actorID, userID string) error {
+	return db.g.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		n, err := gorm.G[Handler](tx).Where("synth_id = ? AND discord_user_id = ?", synthID, userID).Delete(ctx)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+
+		return insertAuditEntry(ctx, tx, &AuditEntry{
+			SynthID:  synthID,
+			ActorID:  actorID,
+			Action:   AuditRevokeHandler,
+			TargetID: userID,
+		})
+	})
+}
+
+// GetHandlers gets the handlers of the given Synth, in the order they were granted.
+func (db *DB) GetHandlers(ctx context.Context, synthID uint64) ([]Handler, error) {
+	handlers, err := gorm.G[Handler](db.g).Where("synth_id = ?", synthID).Order("created_at").Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading Handlers: %w", err)
+	}
+	return handlers, nil
+}
+
+// IsHandler returns if the user is a handler of the given Synth.
+func (db *DB) IsHandler(ctx context.Context, synthID uint64, userID string) (bool, error) {
+	n, err := gorm.G[Handler](db.g).Where("synth_id = ? AND discord_user_id = ?", synthID, userID).Count(ctx, "*")
+	if err != nil {
+		return false, fmt.Errorf("loading Handlers: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GrantHandler grants a user control over this Synth.
+func (s *Synth) GrantHandler(ctx context.Context, actorID, userID string) error {
+	return s.db.GrantHandler(ctx, s.ID, actorID, userID)
+}
+
+// RevokeHandler revokes a user's control over this Synth.
+func (s *Synth) RevokeHandler(ctx context.Context, actorID, userID string) error {
+	return s.db.RevokeHandler(ctx, s.ID, actorID, userID)
+}
+
+// Handlers gets the handlers of this Synth.
+func (s *Synth) Handlers(ctx context.Context) ([]Handler, error) {
+	return s.db.GetHandlers(ctx, s.ID)
+}
+
+// IsHandler returns if the user is a handler of this Synth.
+func (s *Synth) IsHandler(ctx context.Context, userID string) (bool, error) {
+	return s.db.IsHandler(ctx, s.ID, userID)
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LockMode is how a Synth's speech is restricted while it is locked.
+type LockMode string
+
+const (
+	// LockNone means the Synth is not locked.
+	LockNone LockMode = ""
+	// LockMute prevents the Synth from saying anything.
+	LockMute LockMode = "mute"
+	// LockPhrases only allows the Synth to say canned phrases.
+	LockPhrases LockMode = "phrases"
+)
+
+// ActiveLock gets the lock mode that is in effect at the given time.
+func (s *Synth) ActiveLock(now time.Time) LockMode {
+	if s.LockedUntil != nil && !now.Before(*s.LockedUntil) {
+		return LockNone
+	}
+	return s.LockMode
+}
+
+// Lock locks this Synth until the given time, or until it is unlocked if until is nil.
+func (s *Synth) Lock(ctx context.Context, mode LockMode, until *time.Time) error {
+	s.LockMode = mode
+	s.LockedUntil = until
+	return s.saveLock(ctx)
+}
+
+// Unlock releases the lock on this Synth.
+func (s *Synth) Unlock(ctx context.Context) error {
+	s.LockMode = LockNone
+	s.LockedUntil = nil
+	return s.saveLock(ctx)
+}
+
+func (s *Synth) saveLock(ctx context.Context) error {
+	_, err := gorm.G[Synth](s.db.g).
+		Where("id = ?", s.ID).
+		Select("lock_mode", "locked_until").
+		Updates(ctx, *s)
+	if err != nil {
+		return fmt.Errorf("saving Synth lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Phrase is a pre-approved phrase in a Synth's library, which it can say on command.
+type Phrase struct {
+	ID      uint64 `gorm:"primary_key;auto_increment"`
+	SynthID uint64 `gorm:"not null;index"`
+	Text    string `gorm:"not null"`
+	// Permitted phrases are the only ones the Synth may say while it is locked to phrases. If none are permitted, the
+	// defaults from the speech package are used instead.
+	Permitted bool `gorm:"not null;default:false"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (db *DB) InsertPhrase(ctx context.Context, p *Phrase) error {
+	return gorm.G[Phrase](db.g).Create(ctx, p)
+}
+
+// GetPhrases gets the phrases in the given Synth's library, in the order they were added.
+func (db *DB) GetPhrases(ctx context.Context, synthID uint64) ([]Phrase, error) {
+	phrases, err := gorm.G[Phrase](db.g).Where("synth_id = ?", synthID).Order("id").Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading Phrases: %w", err)
+	}
+	return phrases, nil
+}
+
+// GetPermittedPhrases gets the phrases the given Synth may say while it is locked to phrases, in the order they were
+// added.
+func (db *DB) GetPermittedPhrases(ctx context.Context, synthID uint64) ([]Phrase, error) {
+	phrases, err := gorm.G[Phrase](db.g).Where("synth_id = ? AND permitted = ?", synthID, true).Order("id").Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading Phrases: %w", err)
+	}
+	return phrases, nil
+}
+
+// SetPhrasePermitted changes whether a phrase of the given Synth is permitted. ErrNotFound is returned if there is no
+// such phrase.
+func (db *DB) SetPhrasePermitted(ctx context.Context, synthID, id uint64, permitted bool) error {
+	n, err := gorm.G[Phrase](db.g).
+		Where("id = ? AND synth_id = ?", id, synthID).
+		Update(ctx, "permitted", permitted)
+	if err != nil {
+		return fmt.Errorf("saving Phrase: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePhrase deletes a phrase from the given Synth's library. ErrNotFound is returned if there is no such phrase.
+func (db *DB) DeletePhrase(ctx context.Context, synthID, id uint64) error {
+	n, err := gorm.G[Phrase](db.g).Where("id = ? AND synth_id = ?", id, synthID).Delete(ctx)
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Phrases gets the phrases in this Synth's library.
+func (s *Synth) Phrases(ctx context.Context) ([]Phrase, error) {
+	return s.db.GetPhrases(ctx, s.ID)
+}
+
+// PermittedPhrases gets the phrases this Synth may say while it is locked to phrases.
+func (s *Synth) PermittedPhrases(ctx context.Context) ([]Phrase, error) {
+	return s.db.GetPermittedPhrases(ctx, s.ID)
+}
+
+// AddPhrase adds a phrase to this Synth's library.
+func (s *Synth) AddPhrase(ctx context.Context, text string, permitted bool) (*Phrase, error) {
+	p := &Phrase{
+		SynthID:   s.ID,
+		Text:      text,
+		Permitted: permitted,
+	}
+	err := s.db.InsertPhrase(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetPhrasePermitted changes whether a phrase in this Synth's library is permitted.
+func (s *Synth) SetPhrasePermitted(ctx context.Context, id uint64, permitted bool) error {
+	return s.db.SetPhrasePermitted(ctx, s.ID, id, permitted)
+}
+
+// DeletePhrase deletes a phrase from this Synth's library.
+func (s *Synth) DeletePhrase(ctx context.Context, id uint64) error {
+	return s.db.DeletePhrase(ctx, s.ID, id)
+}
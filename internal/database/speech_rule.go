@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SpeechRule is a rule that is applied to what a Synth says in a particular guild. Kind, Action, and Param are
+// interpreted by the speech package.
+type SpeechRule struct {
+	ID      uint64 `gorm:"primary_key;auto_increment"`
+	SynthID uint64 `gorm:"not null;index:idx_speech_rules_synth_guild"`
+	GuildID string `gorm:"not null;index:idx_speech_rules_synth_guild"`
+	Kind    string `gorm:"not null"`
+	Action  string `gorm:"not null"`
+	Param   string `gorm:"not null;default:''"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (db *DB) InsertSpeechRule(ctx context.Context, r *SpeechRule) error {
+	return gorm.G[SpeechRule](db.g).Create(ctx, r)
+}
+
+// GetSpeechRules gets the rules for the given Synth in the given guild, in the order they were added.
+func (db *DB) GetSpeechRules(ctx context.Context, synthID uint64, guildID string) ([]SpeechRule, error) {
+	rules, err := gorm.G[SpeechRule](db.g).
+		Where("synth_id = ? AND guild_id = ?", synthID, guildID).
+		Order("id").
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading SpeechRules: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteSpeechRule deletes a rule for the given Synth in the given guild. ErrNotFound is returned if there is no such
+// rule.
+func (db *DB) DeleteSpeechRule(ctx context.Context, synthID uint64, guildID string, id uint64) error {
+	n, err := gorm.G[SpeechRule](db.g).
+		Where("id = ? AND synth_id = ? AND guild_id = ?", id, synthID, guildID).
+		Delete(ctx)
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SpeechRules gets the rules for this Synth in the given guild.
+func (s *Synth) SpeechRules(ctx context.Context, guildID string) ([]SpeechRule, error) {
+	return s.db.GetSpeechRules(ctx, s.ID, guildID)
+}
+
+// AddSpeechRule adds a rule for this Synth in the given guild.
+func (s *Synth) AddSpeechRule(ctx context.Context, guildID, kind, action, param string) (*SpeechRule, error) {
+	r := &SpeechRule{
+		SynthID: s.ID,
+		GuildID: guildID,
+		Kind:    kind,
+		Action:  action,
+		Param:   param,
+	}
+	err := s.db.InsertSpeechRule(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DeleteSpeechRule deletes a rule for this Synth in the given guild.
+func (s *Synth) DeleteSpeechRule(ctx context.Context, guildID string, id uint64) error {
+	return s.db.DeleteSpeechRule(ctx, s.ID, guildID, id)
+}
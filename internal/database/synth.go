@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
@@ -18,6 +17,10 @@ type Synth struct {
 	Token         string `gorm:"not null"`
 	Enabled       bool   `gorm:"not null"`
 	AllowLogging  bool   `gorm:"not null;default:false"`
+	// LockMode restricts what the Synth can say everywhere. See ActiveLock.
+	LockMode LockMode `gorm:"not null;default:''"`
+	// LockedUntil is when the lock is automatically released, or nil if it must be released manually.
+	LockedUntil *time.Time
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -47,7 +50,7 @@ func (db *DB) GetSynth(ctx context.Context, userID string) (*Synth, error) {
 		return nil, ErrNotFound
 	} else if len(t) != 1 {
 		// database unique constraint should prevent this from ever happening
-		log.Ctx(ctx).Panic().Str("user_id", userID).Msg("Found more than one Synth for user")
+		return nil, fmt.Errorf("found %d Synths for user %s", len(t), userID)
 	}
 
 	s := &t[0]
@@ -55,11 +58,16 @@ func (db *DB) GetSynth(ctx context.Context, userID string) (*Synth, error) {
 	return s, nil
 }
 
-// GetEnabledSynths gets all enabled Synths. TODO pagination
-func (db *DB) GetEnabledSynths(ctx context.Context) ([]*Synth, error) {
-	synths, err := gorm.G[Synth](db.g).Find(ctx)
+// GetEnabledSynths gets a page of the enabled Synths, in order of ID. after is the ID of the last Synth on the previous
+// page, or 0 for the first page. The last page has fewer than limit Synths.
+func (db *DB) GetEnabledSynths(ctx context.Context, after uint64, limit int) ([]*Synth, error) {
+	synths, err := gorm.G[Synth](db.g).
+		Where("enabled = ? AND id > ?", true, after).
+		Order("id").
+		Limit(limit).
+		Find(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("loading Synths: %w", err)
 	}
 
 	ret := make([]*Synth, 0, len(synths))
@@ -78,3 +86,86 @@ func (s *Synth) Save(ctx context.Context) error {
 		Updates(ctx, *s)
 	return err
 }
+
+// DeleteSynth deletes the Synth of a user, along with everything that belongs to it. ErrNotFound is returned if the
+// user does not have a Synth.
+func (db *DB) DeleteSynth(ctx context.Context, userID string) error {
+	return db.g.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		synths, err := gorm.G[Synth](tx).Where("discord_user_id = ?", userID).Find(ctx)
+		if err != nil {
+			return fmt.Errorf("loading Synth: %w", err)
+		} else if len(synths) == 0 {
+			return ErrNotFound
+		}
+
+		for _, s := range synths {
+			for _, del := range []func(context.Context, *gorm.DB, uint64) error{
+				deleteOwnedBy[GuildSettings],
+				deleteOwnedBy[Energy],
+				deleteOwnedBy[SpeechRule],
+				deleteOwnedBy[Handler],
+				deleteOwnedBy[AuditEntry],
+				deleteOwnedBy[Phrase],
+			} {
+				err = del(ctx, tx, s.ID)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = gorm.G[Synth](tx).Where("id = ?", s.ID).Delete(ctx)
+			if err != nil {
+				return fmt.Errorf("deleting Synth: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// deleteOwnedBy deletes the rows of a table that belong to a Synth.
+func deleteOwnedBy[T any](ctx context.Context, tx *gorm.DB, synthID uint64) error {
+	_, err := gorm.G[T](tx).Where("synth_id = ?", synthID).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("deleting %T: %w", *new(T), err)
+	}
+	return nil
+}
+
+// SetToken changes the token of the Synth, and the ID of the application the token is for.
+func (s *Synth) SetToken(ctx context.Context, appID, token string) error {
+	s.ApplicationID = appID
+	s.Token = token
+	_, err := gorm.G[Synth](s.db.g).
+		Where("id = ?", s.ID).
+		Select("application_id", "token").
+		Updates(ctx, *s)
+	if err != nil {
+		return fmt.Errorf("saving Synth token: %w", err)
+	}
+	return nil
+}
+
+// SetAllowLogging changes whether what the Synth says may be logged.
+func (s *Synth) SetAllowLogging(ctx context.Context, allow bool) error {
+	s.AllowLogging = allow
+	_, err := gorm.G[Synth](s.db.g).
+		Where("id = ?", s.ID).
+		Select("allow_logging").
+		Updates(ctx, *s)
+	if err != nil {
+		return fmt.Errorf("saving Synth allow logging: %w", err)
+	}
+	return nil
+}
+
+// SetEnabled changes whether the Synth is enabled. Disabled Synths are not started.
+func (s *Synth) SetEnabled(ctx context.Context, enabled bool) error {
+	s.Enabled = enabled
+	_, err := gorm.G[Synth](s.db.g).
+		Where("id = ?", s.ID).
+		Select("enabled").
+		Updates(ctx, *s)
+	if err != nil {
+		return fmt.Errorf("saving Synth enabled: %w", err)
+	}
+	return nil
+}
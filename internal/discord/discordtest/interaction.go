@@ -0,0 +1,159 @@
+package discordtest
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// interaction makes an interaction from a user, in a guild or in a DM with the bot if guildID is "".
+func interaction(t discordgo.InteractionType, u *discordgo.User, guildID string, data discordgo.InteractionData) *discordgo.InteractionCreate {
+	i := &discordgo.Interaction{
+		ID:        NewID(),
+		Type:      t,
+		Data:      data,
+		GuildID:   guildID,
+		ChannelID: NewID(),
+		Locale:    discordgo.EnglishUS,
+		Token:     "token",
+	}
+	// like Discord, a Member is only given in guilds
+	if guildID != "" {
+		i.Member = &discordgo.Member{GuildID: guildID, User: u}
+	} else {
+		i.User = u
+	}
+	return &discordgo.InteractionCreate{Interaction: i}
+}
+
+// Command makes the interaction for a user using a slash command. Options for subcommands can be made with Subcommand.
+func Command(u *discordgo.User, guildID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return interaction(discordgo.InteractionApplicationCommand, u, guildID, discordgo.ApplicationCommandInteractionData{
+		ID:          NewID(),
+		Name:        name,
+		CommandType: discordgo.ChatApplicationCommand,
+		Options:     options,
+	})
+}
+
+// Autocomplete makes the interaction for autocompleting the focused option of a slash command.
+func Autocomplete(u *discordgo.User, guildID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	i := Command(u, guildID, name, options...)
+	i.Type = discordgo.InteractionApplicationCommandAutocomplete
+	return i
+}
+
+// UserCommand makes the interaction for a user using a user context menu command on target.
+func UserCommand(u *discordgo.User, guildID, name string, target *discordgo.User) *discordgo.InteractionCreate {
+	return interaction(discordgo.InteractionApplicationCommand, u, guildID, discordgo.ApplicationCommandInteractionData{
+		ID:          NewID(),
+		Name:        name,
+		CommandType: discordgo.UserApplicationCommand,
+		TargetID:    target.ID,
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Users: map[string]*discordgo.User{target.ID: target},
+		},
+	})
+}
+
+// MessageCommand makes the interaction for a user using a message context menu command on target.
+func MessageCommand(u *discordgo.User, guildID, name string, target *discordgo.Message) *discordgo.InteractionCreate {
+	return interaction(discordgo.InteractionApplicationCommand, u, guildID, discordgo.ApplicationCommandInteractionData{
+		ID:          NewID(),
+		Name:        name,
+		CommandType: discordgo.MessageApplicationCommand,
+		TargetID:    target.ID,
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Messages: map[string]*discordgo.Message{target.ID: target},
+		},
+	})
+}
+
+// Subcommand makes the option for using a subcommand, or a subcommand group if its options are subcommands.
+func Subcommand(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	t := discordgo.ApplicationCommandOptionSubCommand
+	if len(options) > 0 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		t = discordgo.ApplicationCommandOptionSubCommandGroup
+	}
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:    name,
+		Type:    t,
+		Options: options,
+	}
+}
+
+// String makes a string option.
+func String(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionString,
+		Value: value,
+	}
+}
+
+// Int makes an integer option.
+func Int(name string, value int64) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name: name,
+		Type: discordgo.ApplicationCommandOptionInteger,
+		// numbers are decoded from JSON as float64
+		Value: float64(value),
+	}
+}
+
+// Bool makes a boolean option.
+func Bool(name string, value bool) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionBoolean,
+		Value: value,
+	}
+}
+
+// User makes a user option. Only the ID of the user is given, as if Discord had not resolved it.
+func User(name string, u *discordgo.User) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionUser,
+		Value: u.ID,
+	}
+}
+
+// Focused marks an option as the one being autocompleted.
+func Focused(o *discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	o.Focused = true
+	return o
+}
+
+// Button makes the interaction for a user pressing a button.
+func Button(u *discordgo.User, guildID, customID string) *discordgo.InteractionCreate {
+	return interaction(discordgo.InteractionMessageComponent, u, guildID, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: discordgo.ButtonComponent,
+	})
+}
+
+// Select makes the interaction for a user choosing values in a string select menu.
+func Select(u *discordgo.User, guildID, customID string, values ...string) *discordgo.InteractionCreate {
+	return interaction(discordgo.InteractionMessageComponent, u, guildID, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: discordgo.SelectMenuComponent,
+		Values:        values,
+	})
+}
+
+// ModalSubmit makes the interaction for a user submitting a modal. fields are the values of its text inputs by custom
+// ID, each of which is given in its own label.
+func ModalSubmit(u *discordgo.User, guildID, customID string, fields map[string]string) *discordgo.InteractionCreate {
+	var components []discordgo.MessageComponent
+	for id, value := range fields {
+		components = append(components, &discordgo.Label{
+			Component: &discordgo.TextInput{
+				CustomID: id,
+				Value:    value,
+			},
+		})
+	}
+	return interaction(discordgo.InteractionModalSubmit, u, guildID, discordgo.ModalSubmitInteractionData{
+		CustomID:   customID,
+		Components: components,
+	})
+}
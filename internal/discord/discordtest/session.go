@@ -0,0 +1,552 @@
+// Package discordtest provides a fake Discord, so that code using discord.Session can be tested without connecting to
+// Discord.
+package discordtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/discord"
+)
+
+var _ discord.Session = (*Session)(nil)
+
+// ids are shared by everything the fake creates, so that IDs are unique across Sessions like Discord's snowflakes.
+var ids atomic.Uint64
+
+// NewID makes a new, unique ID.
+func NewID() string {
+	return strconv.FormatUint(1_000_000+ids.Add(1), 10)
+}
+
+// Session is an in-memory discord.Session for a bot. It serves the channels, messages, members, and files that tests
+// add to it, and records what the bot did so that tests can check it. Like Discord, an interaction can only be
+// responded to once.
+//
+// It is safe to use from multiple goroutines.
+type Session struct {
+	mu sync.Mutex
+
+	me *discordgo.User
+
+	channels map[string]*discordgo.Channel
+	messages map[string]*discordgo.Message
+	members  map[memberKey]*discordgo.Member
+	files    map[string][]byte
+	// commands are the registered application commands of each guild, with "" for the global commands.
+	commands map[string][]*discordgo.ApplicationCommand
+
+	responses map[string][]*discordgo.InteractionResponse
+	edits     map[string][]*discordgo.WebhookEdit
+	sent      []*discordgo.Message
+	deleted   []string
+	status    discordgo.UpdateStatusData
+
+	calls    map[string]int
+	failures map[string]error
+}
+
+type memberKey struct {
+	guildID, userID string
+}
+
+// New makes a Session for a bot with the given username.
+func New(username string) *Session {
+	return &Session{
+		me: &discordgo.User{
+			ID:       NewID(),
+			Username: username,
+			Bot:      true,
+		},
+		channels:  make(map[string]*discordgo.Channel),
+		messages:  make(map[string]*discordgo.Message),
+		members:   make(map[memberKey]*discordgo.Member),
+		files:     make(map[string][]byte),
+		commands:  make(map[string][]*discordgo.ApplicationCommand),
+		responses: make(map[string][]*discordgo.InteractionResponse),
+		edits:     make(map[string][]*discordgo.WebhookEdit),
+		calls:     make(map[string]int),
+		failures:  make(map[string]error),
+	}
+}
+
+// NewUser makes a user that is not a bot.
+func NewUser(username string) *discordgo.User {
+	return &discordgo.User{
+		ID:       NewID(),
+		Username: username,
+	}
+}
+
+// restError makes an error like the ones discordgo returns for failed requests.
+func restError(status, code int, msg string) *discordgo.RESTError {
+	return &discordgo.RESTError{
+		Response: &http.Response{
+			StatusCode: status,
+			Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		},
+		ResponseBody: fmt.Appendf(nil, `{"code": %d, "message": %q}`, code, msg),
+		Message: &discordgo.APIErrorMessage{
+			Code:    code,
+			Message: msg,
+		},
+	}
+}
+
+// call records a call to a method, returning the error it should fail with, if any. mu must be held.
+func (s *Session) call(method string) error {
+	s.calls[method]++
+	return s.failures[method]
+}
+
+// Fail makes every call to a method fail with err, until it is called again with a nil err.
+func (s *Session) Fail(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, method)
+	} else {
+		s.failures[method] = err
+	}
+}
+
+// Calls gets how many times a method has been called.
+func (s *Session) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// AddChannel adds a channel of the given type in a guild, or a channel outside of any guild if guildID is "".
+func (s *Session) AddChannel(guildID string, t discordgo.ChannelType) *discordgo.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &discordgo.Channel{
+		ID:      NewID(),
+		GuildID: guildID,
+		Type:    t,
+	}
+	s.channels[c.ID] = c
+	return c
+}
+
+// AddMember adds a member to a guild.
+func (s *Session) AddMember(guildID string, m *discordgo.Member) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.GuildID = guildID
+	s.members[memberKey{guildID, m.User.ID}] = m
+}
+
+// AddFile makes a file, such as an attachment, downloadable from url.
+func (s *Session) AddFile(url string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[url] = body
+}
+
+// Post posts a message to a channel as a user, returning the event the gateway would send for it.
+func (s *Session) Post(channelID string, author *discordgo.User, content string) *discordgo.MessageCreate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := &discordgo.Message{
+		ID:        NewID(),
+		ChannelID: channelID,
+		Content:   content,
+		Author:    author,
+	}
+	if c, ok := s.channels[channelID]; ok {
+		m.GuildID = c.GuildID
+	}
+	s.messages[m.ID] = m
+	cp := *m
+	return &discordgo.MessageCreate{Message: &cp}
+}
+
+// Message gets a message by ID, or nil if there is no such message or it was deleted.
+func (s *Session) Message(id string) *discordgo.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages[id]
+}
+
+// Sent gets the messages the bot sent, in order.
+func (s *Session) Sent() []*discordgo.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.sent)
+}
+
+// Deleted gets the IDs of the messages the bot deleted, in order.
+func (s *Session) Deleted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.deleted)
+}
+
+// Responses gets the responses to an interaction, in order. Only the first was accepted.
+func (s *Session) Responses(interactionID string) []*discordgo.InteractionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.responses[interactionID])
+}
+
+// Response gets the response to an interaction, or nil if it was not responded to.
+func (s *Session) Response(interactionID string) *discordgo.InteractionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rs := s.responses[interactionID]; len(rs) > 0 {
+		return rs[0]
+	}
+	return nil
+}
+
+// ResponseEdits gets the edits made to the response to an interaction, in order.
+func (s *Session) ResponseEdits(interactionID string) []*discordgo.WebhookEdit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.edits[interactionID])
+}
+
+// Commands gets the application commands registered in a guild, or the global commands if guildID is "".
+func (s *Session) Commands(guildID string) []*discordgo.ApplicationCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands[guildID])
+}
+
+// Status gets the status the bot most recently set.
+func (s *Session) Status() discordgo.UpdateStatusData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *Session) Me() *discordgo.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.me
+}
+
+func (s *Session) StateMember(guildID, userID string) (*discordgo.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.members[memberKey{guildID, userID}]
+	if !ok {
+		return nil, discordgo.ErrStateNotFound
+	}
+	return m, nil
+}
+
+func (s *Session) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("InteractionRespond"); err != nil {
+		return err
+	}
+	s.responses[interaction.ID] = append(s.responses[interaction.ID], resp)
+	if len(s.responses[interaction.ID]) > 1 {
+		return restError(http.StatusBadRequest, discordgo.ErrCodeInteractionHasAlreadyBeenAcknowledged,
+			"Interaction has already been acknowledged.")
+	}
+	return nil
+}
+
+func (s *Session) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("InteractionResponseEdit"); err != nil {
+		return nil, err
+	}
+	if len(s.responses[interaction.ID]) == 0 {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownInteraction, "Unknown interaction")
+	}
+	s.edits[interaction.ID] = append(s.edits[interaction.ID], newresp)
+	m := &discordgo.Message{
+		ID:        interaction.ID,
+		ChannelID: interaction.ChannelID,
+		Author:    s.me,
+	}
+	if newresp.Content != nil {
+		m.Content = *newresp.Content
+	}
+	return m, nil
+}
+
+func (s *Session) ApplicationCommands(appID, guildID string, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ApplicationCommands"); err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.commands[guildID]), nil
+}
+
+func (s *Session) ApplicationCommandCreate(appID string, guildID string, cmd *discordgo.ApplicationCommand, _ ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ApplicationCommandCreate"); err != nil {
+		return nil, err
+	}
+	c := *cmd
+	c.ID = NewID()
+	c.ApplicationID = appID
+	c.GuildID = guildID
+	s.commands[guildID] = append(s.commands[guildID], &c)
+	return &c, nil
+}
+
+func (s *Session) ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, _ ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ApplicationCommandEdit"); err != nil {
+		return nil, err
+	}
+	j := slices.IndexFunc(s.commands[guildID], func(c *discordgo.ApplicationCommand) bool { return c.ID == cmdID })
+	if j < 0 {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownApplicationCommand, "Unknown application command")
+	}
+	c := *cmd
+	c.ID = cmdID
+	c.ApplicationID = appID
+	c.GuildID = guildID
+	s.commands[guildID][j] = &c
+	return &c, nil
+}
+
+func (s *Session) ApplicationCommandDelete(appID, guildID, cmdID string, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ApplicationCommandDelete"); err != nil {
+		return err
+	}
+	j := slices.IndexFunc(s.commands[guildID], func(c *discordgo.ApplicationCommand) bool { return c.ID == cmdID })
+	if j < 0 {
+		return restError(http.StatusNotFound, discordgo.ErrCodeUnknownApplicationCommand, "Unknown application command")
+	}
+	s.commands[guildID] = slices.Delete(s.commands[guildID], j, j+1)
+	return nil
+}
+
+func (s *Session) Channel(channelID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("Channel"); err != nil {
+		return nil, err
+	}
+	c, ok := s.channels[channelID]
+	if !ok {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownChannel, "Unknown Channel")
+	}
+	return c, nil
+}
+
+func (s *Session) ChannelMessage(channelID, messageID string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ChannelMessage"); err != nil {
+		return nil, err
+	}
+	m, ok := s.messages[messageID]
+	if !ok || m.ChannelID != channelID {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownMessage, "Unknown Message")
+	}
+	return m, nil
+}
+
+func (s *Session) ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content}, options...)
+}
+
+func (s *Session) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ChannelMessageSendComplex"); err != nil {
+		return nil, err
+	}
+	c, ok := s.channels[channelID]
+	if !ok {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownChannel, "Unknown Channel")
+	}
+
+	m := &discordgo.Message{
+		ID:               NewID(),
+		ChannelID:        channelID,
+		GuildID:          c.GuildID,
+		Content:          data.Content,
+		Author:           s.me,
+		MessageReference: data.Reference,
+		Flags:            data.Flags,
+		Components:       data.Components,
+		Poll:             data.Poll,
+	}
+	for _, f := range data.Files {
+		m.Attachments = append(m.Attachments, &discordgo.MessageAttachment{
+			ID:          NewID(),
+			Filename:    f.Name,
+			ContentType: f.ContentType,
+		})
+	}
+	for _, id := range data.StickerIDs {
+		m.StickerItems = append(m.StickerItems, &discordgo.StickerItem{ID: id})
+	}
+	s.messages[m.ID] = m
+	s.sent = append(s.sent, m)
+	return m, nil
+}
+
+func (s *Session) ChannelMessageEditComplex(e *discordgo.MessageEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ChannelMessageEditComplex"); err != nil {
+		return nil, err
+	}
+	m, ok := s.messages[e.ID]
+	if !ok || m.ChannelID != e.Channel {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownMessage, "Unknown Message")
+	}
+	if m.Author == nil || m.Author.ID != s.me.ID {
+		return nil, restError(http.StatusForbidden, discordgo.ErrCodeCannotEditFromAnotherUser,
+			"Cannot edit a message authored by another user")
+	}
+	if e.Content != nil {
+		m.Content = *e.Content
+	}
+	if e.Components != nil {
+		m.Components = *e.Components
+	}
+	return m, nil
+}
+
+func (s *Session) ChannelMessageDelete(channelID, messageID string, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ChannelMessageDelete"); err != nil {
+		return err
+	}
+	m, ok := s.messages[messageID]
+	if !ok || m.ChannelID != channelID {
+		return restError(http.StatusNotFound, discordgo.ErrCodeUnknownMessage, "Unknown Message")
+	}
+	delete(s.messages, messageID)
+	s.deleted = append(s.deleted, messageID)
+	return nil
+}
+
+func (s *Session) UserChannelCreate(recipientID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("UserChannelCreate"); err != nil {
+		return nil, err
+	}
+	for _, c := range s.channels {
+		if c.Type == discordgo.ChannelTypeDM && len(c.Recipients) == 1 && c.Recipients[0].ID == recipientID {
+			return c, nil
+		}
+	}
+	c := &discordgo.Channel{
+		ID:         NewID(),
+		Type:       discordgo.ChannelTypeDM,
+		Recipients: []*discordgo.User{{ID: recipientID}},
+	}
+	s.channels[c.ID] = c
+	return c, nil
+}
+
+func (s *Session) GuildMember(guildID, userID string, _ ...discordgo.RequestOption) (*discordgo.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GuildMember"); err != nil {
+		return nil, err
+	}
+	m, ok := s.members[memberKey{guildID, userID}]
+	if !ok {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownMember, "Unknown Member")
+	}
+	return m, nil
+}
+
+func (s *Session) GuildMemberNickname(guildID, userID, nickname string, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GuildMemberNickname"); err != nil {
+		return err
+	}
+	if userID == "@me" {
+		userID = s.me.ID
+	}
+	m, ok := s.members[memberKey{guildID, userID}]
+	if !ok {
+		return restError(http.StatusNotFound, discordgo.ErrCodeUnknownMember, "Unknown Member")
+	}
+	m.Nick = nickname
+	return nil
+}
+
+func (s *Session) GuildCurrentMemberEdit(guildID string, data *discordgo.GuildCurrentMemberParams, _ ...discordgo.RequestOption) (*discordgo.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GuildCurrentMemberEdit"); err != nil {
+		return nil, err
+	}
+	m, ok := s.members[memberKey{guildID, s.me.ID}]
+	if !ok {
+		return nil, restError(http.StatusNotFound, discordgo.ErrCodeUnknownMember, "Unknown Member")
+	}
+	if data.Nick != nil {
+		m.Nick = *data.Nick
+	}
+	if data.Avatar != nil {
+		m.Avatar = *data.Avatar
+	}
+	return m, nil
+}
+
+func (s *Session) UserUpdate(username, avatar, banner string, _ ...discordgo.RequestOption) (*discordgo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("UserUpdate"); err != nil {
+		return nil, err
+	}
+	u := *s.me
+	if username != "" {
+		u.Username = username
+	}
+	if avatar != "" {
+		u.Avatar = avatar
+	}
+	if banner != "" {
+		u.Banner = banner
+	}
+	s.me = &u
+	return s.me, nil
+}
+
+func (s *Session) UpdateStatusComplex(usd discordgo.UpdateStatusData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("UpdateStatusComplex"); err != nil {
+		return err
+	}
+	s.status = usd
+	return nil
+}
+
+func (s *Session) RequestWithBucketID(method, urlStr string, _ any, _ string, _ ...discordgo.RequestOption) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("RequestWithBucketID"); err != nil {
+		return nil, err
+	}
+	body, ok := s.files[urlStr]
+	if method != http.MethodGet || !ok {
+		return nil, restError(http.StatusNotFound, 0, "404: Not Found")
+	}
+	return body, nil
+}
@@ -0,0 +1,66 @@
+// Package discord abstracts the parts of discordgo.Session that the bots use, so that they can be tested without
+// connecting to Discord.
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session is the subset of *discordgo.Session that the bots use. The methods have the same signatures as discordgo's,
+// so see it for documentation.
+type Session interface {
+	// Me gets the bot's own user. It is nil until the session is ready.
+	Me() *discordgo.User
+	// StateMember gets a guild member from the state cache, without making a request.
+	StateMember(guildID, userID string) (*discordgo.Member, error)
+
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandCreate(appID string, guildID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandDelete(appID, guildID, cmdID string, options ...discordgo.RequestOption) error
+
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	ChannelMessage(channelID, messageID string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+
+	GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	GuildMemberNickname(guildID, userID, nickname string, options ...discordgo.RequestOption) error
+	GuildCurrentMemberEdit(guildID string, data *discordgo.GuildCurrentMemberParams, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	UserUpdate(username, avatar, banner string, options ...discordgo.RequestOption) (*discordgo.User, error)
+	UpdateStatusComplex(usd discordgo.UpdateStatusData) error
+
+	// RequestWithBucketID is used to download attachments and avatars.
+	RequestWithBucketID(method, urlStr string, data any, bucketID string, options ...discordgo.RequestOption) ([]byte, error)
+}
+
+// session adapts a *discordgo.Session to Session.
+type session struct {
+	*discordgo.Session
+}
+
+func (s session) Me() *discordgo.User {
+	return s.State.User
+}
+
+func (s session) StateMember(guildID, userID string) (*discordgo.Member, error) {
+	return s.State.Member(guildID, userID)
+}
+
+// Wrap adapts a *discordgo.Session to Session.
+func Wrap(s *discordgo.Session) Session {
+	return session{s}
+}
+
+// Handler adapts an event handler that takes a Session so that it can be added with discordgo.Session.AddHandler.
+func Handler[E any](h func(Session, E)) func(*discordgo.Session, E) {
+	return func(s *discordgo.Session, e E) {
+		h(Wrap(s), e)
+	}
+}
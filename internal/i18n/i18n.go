@@ -0,0 +1,156 @@
+// Package i18n provides translations of the text SynthOS shows to users, from message catalogs in the locales
+// directory. Each catalog is a TOML file named for its Discord locale, such as en-US.toml, and its tables are flattened
+// into dotted keys, such as "setup.start".
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultLocale is the locale used when a message is not available in the user's locale. Command names and
+// descriptions are registered in it, with the other locales as localizations.
+const DefaultLocale = discordgo.EnglishUS
+
+//go:embed locales/*.toml
+var locales embed.FS
+
+// Catalog holds the messages for every locale.
+type Catalog struct {
+	messages map[discordgo.Locale]map[string]string
+	// locales are the locales with catalogs, sorted, so that falling back to another locale is predictable.
+	locales []discordgo.Locale
+}
+
+// Load loads the catalogs in the root of fsys. The catalog for DefaultLocale is required.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.toml")
+	if err != nil {
+		return nil, fmt.Errorf("finding catalogs: %w", err)
+	}
+
+	c := &Catalog{messages: make(map[discordgo.Locale]map[string]string)}
+	for _, file := range files {
+		var raw map[string]any
+		_, err = toml.DecodeFS(fsys, file, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("loading catalog %s: %w", file, err)
+		}
+
+		messages := make(map[string]string)
+		err = flatten("", raw, messages)
+		if err != nil {
+			return nil, fmt.Errorf("loading catalog %s: %w", file, err)
+		}
+		locale := discordgo.Locale(strings.TrimSuffix(path.Base(file), ".toml"))
+		c.messages[locale] = messages
+		c.locales = append(c.locales, locale)
+	}
+	slices.Sort(c.locales)
+
+	if _, ok := c.messages[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("no catalog for default locale %s", DefaultLocale)
+	}
+	return c, nil
+}
+
+// flatten adds the messages in a decoded TOML table to messages, with their keys prefixed by the table's key.
+func flatten(prefix string, table map[string]any, messages map[string]string) error {
+	for k, v := range table {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			messages[key] = v
+		case map[string]any:
+			err := flatten(key, v, messages)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s is a %T, not a string or table", key, v)
+		}
+	}
+	return nil
+}
+
+var defaultCatalog = sync.OnceValue(func() *Catalog {
+	sub, err := fs.Sub(locales, "locales")
+	if err != nil {
+		log.Panic().Err(err).Msg("Error loading embedded catalogs")
+	}
+	c, err := Load(sub)
+	if err != nil {
+		log.Panic().Err(err).Msg("Error loading embedded catalogs")
+	}
+	return c
+})
+
+// Default gets the catalog built into SynthOS.
+func Default() *Catalog {
+	return defaultCatalog()
+}
+
+// T translates a message from the built-in catalog. See Catalog.T.
+func T(locale discordgo.Locale, key string, args ...any) string {
+	return Default().T(locale, key, args...)
+}
+
+// lookup finds a message in the locale, or in another locale for the same language, or in DefaultLocale. The other
+// locales are tried in order, so a catalog for just the language, such as "es", comes before "es-ES".
+func (c *Catalog) lookup(locale discordgo.Locale, key string) (string, bool) {
+	if msg, ok := c.messages[locale][key]; ok {
+		return msg, true
+	}
+	// such as en-GB for en-US, or es-ES for es-419
+	lang, _, _ := strings.Cut(string(locale), "-")
+	for _, l := range c.locales {
+		if msg, ok := c.messages[l][key]; ok && strings.HasPrefix(string(l)+"-", lang+"-") {
+			return msg, true
+		}
+	}
+	msg, ok := c.messages[DefaultLocale][key]
+	return msg, ok
+}
+
+// T translates a message into the given locale, falling back to DefaultLocale. If there are args, the message is used
+// as a format string for them. The key itself is returned if there is no such message, so a mistake is visible without
+// breaking anything.
+func (c *Catalog) T(locale discordgo.Locale, key string, args ...any) string {
+	msg, ok := c.lookup(locale, key)
+	if !ok {
+		log.Warn().Str("key", key).Msg("Missing message")
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Default gets a message in DefaultLocale, or "" if there is no such message.
+func (c *Catalog) Default(key string) string {
+	return c.messages[DefaultLocale][key]
+}
+
+// Localizations gets a message in every locale other than DefaultLocale that has it.
+func (c *Catalog) Localizations(key string) map[discordgo.Locale]string {
+	l := make(map[discordgo.Locale]string)
+	for locale, messages := range c.messages {
+		if msg, ok := messages[key]; ok && locale != DefaultLocale {
+			l[locale] = msg
+		}
+	}
+	return l
+}
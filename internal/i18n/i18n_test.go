@@ -0,0 +1,74 @@
+package i18n_test
+
+import (
+	"maps"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/ajanata/synthos/internal/i18n"
+)
+
+func TestCatalog(t *testing.T) {
+	c, err := i18n.Load(fstest.MapFS{
+		"en-US.toml": {Data: []byte(`
+hello = "Hello"
+count = "%d synths"
+only_default = "Default"
+
+[setup]
+start = "Start"
+`)},
+		"es.toml":    {Data: []byte(`hello = "Hola"`)},
+		"es-ES.toml": {Data: []byte(`hello = "Hola, España"` + "\n" + `count = "%d synths en España"`)},
+		"fr.toml":    {Data: []byte(`count = "%d synths en France"`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		locale discordgo.Locale
+		key    string
+		args   []any
+		want   string
+	}{
+		{discordgo.EnglishUS, "hello", nil, "Hello"},
+		{discordgo.EnglishUS, "setup.start", nil, "Start"},
+		{discordgo.SpanishES, "hello", nil, "Hola, España"},
+		// the catalog for just the language is preferred over other regions
+		{discordgo.SpanishLATAM, "hello", nil, "Hola"},
+		{discordgo.SpanishLATAM, "count", []any{3}, "3 synths en España"},
+		{discordgo.French, "count", []any{2}, "2 synths en France"},
+		{discordgo.French, "hello", nil, "Hello"},
+		{discordgo.German, "only_default", nil, "Default"},
+		{discordgo.German, "missing.key", nil, "missing.key"},
+	} {
+		// the fallback must not depend on the order a map is ranged over
+		for range 10 {
+			if got := c.T(tc.locale, tc.key, tc.args...); got != tc.want {
+				t.Errorf("T(%s, %s) = %q, want %q", tc.locale, tc.key, got, tc.want)
+				break
+			}
+		}
+	}
+
+	want := map[discordgo.Locale]string{"es": "Hola", discordgo.SpanishES: "Hola, España"}
+	if got := c.Localizations("hello"); !maps.Equal(got, want) {
+		t.Errorf("Localizations = %v, want %v", got, want)
+	}
+}
+
+func TestLoadRequiresDefaultLocale(t *testing.T) {
+	_, err := i18n.Load(fstest.MapFS{"fr.toml": {Data: []byte(`hello = "Bonjour"`)}})
+	if err == nil {
+		t.Error("loaded catalogs without the default locale")
+	}
+}
+
+func TestBuiltInCatalog(t *testing.T) {
+	if got := i18n.T(discordgo.EnglishUS, "configure.change"); got != "Change" {
+		t.Errorf("got %q from the built-in catalog", got)
+	}
+}
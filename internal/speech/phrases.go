@@ -0,0 +1,47 @@
+package speech
+
+import (
+	"strings"
+)
+
+// DefaultPhrases are the phrases a Synth may say when it is restricted to phrases and has none of its own.
+var DefaultPhrases = []string{
+	"Yes.",
+	"No.",
+	"Acknowledged.",
+	"This unit is unable to comply.",
+	"This unit's speech is restricted.",
+}
+
+// Phrases only permits messages that are one of a fixed set of phrases. Messages are matched ignoring case and
+// punctuation, and are replaced with the phrase exactly as it was given.
+type Phrases struct {
+	Phrases []string
+}
+
+var _ Rule = (*Phrases)(nil)
+
+func (p *Phrases) Apply(m *Message) error {
+	want := normalizePhrase(m.Content)
+	for _, phrase := range p.Phrases {
+		if normalizePhrase(phrase) == want {
+			m.Content = phrase
+			return nil
+		}
+	}
+
+	quoted := make([]string, len(p.Phrases))
+	for i, phrase := range p.Phrases {
+		quoted[i] = `"` + phrase + `"`
+	}
+	return &RejectedError{Reason: "only these phrases are permitted: " + strings.Join(quoted, ", ")}
+}
+
+// normalizePhrase reduces a phrase to its normalized words, so that phrases can be compared.
+func normalizePhrase(s string) string {
+	words := wordRegexp.FindAllString(s, -1)
+	for i, w := range words {
+		words[i] = normalizeWord(w)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,146 @@
+package speech
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultPronounReplacements are used when a Synth has pronoun rewriting enabled but has not customized the
+// replacements.
+var DefaultPronounReplacements = map[string]string{
+	"i":      "this unit",
+	"me":     "this unit",
+	"my":     "this unit's",
+	"mine":   "this unit's",
+	"myself": "this unit",
+	"i'm":    "this unit is",
+	"i've":   "this unit has",
+	"i'll":   "this unit will",
+	"i'd":    "this unit would",
+}
+
+// protectedRegexp matches the parts of a message that must not be rewritten: code blocks, inline code, mentions,
+// custom emoji, and URLs.
+var protectedRegexp = regexp.MustCompile("(?s)```.*?```|`[^`]*`|<[^<>\\s]+>|\\bhttps?://\\S+")
+
+// sentenceEndRegexp matches text that ends with the end of a sentence or line, or is empty.
+var sentenceEndRegexp = regexp.MustCompile(`(?:^|[.!?\n])[\s"'“(*_~]*$`)
+
+// Pronouns rewrites first-person pronouns with replacements, such as speaking in the third person.
+type Pronouns struct {
+	// Replacements maps lowercase pronouns to what they should be replaced with.
+	Replacements map[string]string
+}
+
+var _ Rule = (*Pronouns)(nil)
+
+func (r *Pronouns) Apply(m *Message) error {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range protectedRegexp.FindAllStringIndex(m.Content, -1) {
+		r.rewrite(&sb, m.Content[last:loc[0]])
+		sb.WriteString(m.Content[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	r.rewrite(&sb, m.Content[last:])
+	m.Content = sb.String()
+	return nil
+}
+
+// rewrite writes text to sb, replacing pronouns. sb has everything prior to text already written to it, so that the
+// start of sentences can be detected.
+func (r *Pronouns) rewrite(sb *strings.Builder, text string) {
+	last := 0
+	for _, loc := range wordRegexp.FindAllStringIndex(text, -1) {
+		word := text[loc[0]:loc[1]]
+		replacement, ok := r.Replacements[normalizeWord(word)]
+		if !ok {
+			continue
+		}
+		sb.WriteString(text[last:loc[0]])
+		sb.WriteString(matchCase(word, replacement, sentenceEndRegexp.MatchString(sb.String())))
+		last = loc[1]
+	}
+	sb.WriteString(text[last:])
+}
+
+// matchCase adjusts the capitalization of a replacement to match the word it is replacing.
+func matchCase(word, replacement string, sentenceStart bool) string {
+	first, _ := utf8.DecodeRuneInString(word)
+	switch {
+	case utf8.RuneCountInString(word) > 1 && strings.ToUpper(word) == word:
+		return strings.ToUpper(replacement)
+	case first == 'I':
+		// "I" is always capitalized in English, so that doesn't tell us anything
+		if sentenceStart {
+			return capitalize(replacement)
+		}
+		return replacement
+	case unicode.IsUpper(first):
+		return capitalize(replacement)
+	default:
+		return replacement
+	}
+}
+
+func capitalize(s string) string {
+	first, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(first)) + s[size:]
+}
+
+// ParsePronounReplacements parses replacements in the format produced by FormatPronounReplacements: one replacement
+// per line, in the form "pronoun = replacement".
+func ParsePronounReplacements(s string) (map[string]string, error) {
+	ret := make(map[string]string)
+	for n, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pronoun, replacement, ok := strings.Cut(line, "=")
+		pronoun = normalizeWord(strings.TrimSpace(pronoun))
+		replacement = strings.TrimSpace(replacement)
+		if !ok || pronoun == "" || replacement == "" {
+			return nil, fmt.Errorf("%w: line %d must be in the form \"pronoun = replacement\"", ErrInvalidParam, n+1)
+		}
+		if wordRegexp.FindString(pronoun) != pronoun {
+			return nil, fmt.Errorf("%w: line %d: %q is not a single word", ErrInvalidParam, n+1, pronoun)
+		}
+		ret[pronoun] = replacement
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("%w: at least one replacement is required", ErrInvalidParam)
+	}
+	return ret, nil
+}
+
+// FormatPronounReplacements formats replacements for display and editing, with the first-person pronouns first, in
+// their usual order.
+func FormatPronounReplacements(replacements map[string]string) string {
+	var sb strings.Builder
+	seen := make(map[string]bool)
+	write := func(p string) {
+		if r, ok := replacements[p]; ok && !seen[p] {
+			seen[p] = true
+			sb.WriteString(p + " = " + r + "\n")
+		}
+	}
+	for _, p := range FirstPersonPronouns {
+		write(p)
+	}
+	var rest []string
+	for p := range replacements {
+		if !seen[p] {
+			rest = append(rest, p)
+		}
+	}
+	slices.Sort(rest)
+	for _, p := range rest {
+		write(p)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
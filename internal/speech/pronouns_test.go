@@ -0,0 +1,57 @@
+package speech_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+func TestPronouns(t *testing.T) {
+	r := &speech.Pronouns{Replacements: speech.DefaultPronounReplacements}
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"I am here", "This unit am here"},
+		{"yes, I am", "yes, this unit am"},
+		{"Hi. I'm done! I’ll go", "Hi. This unit is done! This unit will go"},
+		{"that's MINE", "that's THIS UNIT'S"},
+		{"give it to Me", "give it to This unit"},
+		{"my turn", "this unit's turn"},
+		{"*I* did it", "*This unit* did it"},
+		{"mine\nI said", "this unit's\nThis unit said"},
+		{"nothing to change", "nothing to change"},
+		{"ask <@123> and me", "ask <@123> and this unit"},
+		{"`I` wrote https://example.com/me and <:me:456>", "`I` wrote https://example.com/me and <:me:456>"},
+		{"```\nI\n``` then I", "```\nI\n``` then this unit"},
+	} {
+		m := &speech.Message{Content: tc.in}
+		err := r.Apply(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Content != tc.want {
+			t.Errorf("%q became %q, want %q", tc.in, m.Content, tc.want)
+		}
+	}
+}
+
+func TestParsePronounReplacements(t *testing.T) {
+	got, err := speech.ParsePronounReplacements("I = it\n\n  My= its \nI’m = it is")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["i"] != "it" || got["my"] != "its" || got["i'm"] != "it is" {
+		t.Errorf("got %v", got)
+	}
+	if s := speech.FormatPronounReplacements(got); s != "i = it\nmy = its\ni'm = it is" {
+		t.Errorf("formatted as %q", s)
+	}
+
+	for _, in := range []string{"", "I it", "= it", "I =", "two words = it"} {
+		_, err := speech.ParsePronounReplacements(in)
+		if !errors.Is(err, speech.ErrInvalidParam) {
+			t.Errorf("%q returned %v, want it invalid", in, err)
+		}
+	}
+}
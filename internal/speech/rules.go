@@ -0,0 +1,178 @@
+package speech
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind identifies a type of Rule.
+type Kind string
+
+const (
+	KindPrefix        Kind = "prefix"
+	KindAllowlist     Kind = "allowlist"
+	KindForbidden     Kind = "forbidden"
+	KindNoFirstPerson Kind = "no-first-person"
+	KindMaxWords      Kind = "max-words"
+)
+
+// Kinds are all valid Kinds.
+var Kinds = []Kind{KindPrefix, KindAllowlist, KindForbidden, KindNoFirstPerson, KindMaxWords}
+
+var ErrInvalidKind = errors.New("invalid rule kind")
+var ErrInvalidParam = errors.New("invalid rule parameter")
+
+// redacted replaces words that are removed by a rewrite.
+const redacted = "▇▇▇"
+
+// FirstPersonPronouns are the first-person singular pronouns and contractions, in lowercase.
+var FirstPersonPronouns = []string{"i", "me", "my", "mine", "myself", "i'm", "i've", "i'll", "i'd"}
+
+var wordRegexp = regexp.MustCompile(`[\p{L}\p{N}]+(?:['’][\p{L}\p{N}]+)*`)
+
+// normalizeWord lowercases a word and normalizes apostrophes so it can be compared against word lists.
+func normalizeWord(w string) string {
+	return strings.ReplaceAll(strings.ToLower(w), "’", "'")
+}
+
+// words finds the locations of the words in content, skipping the parts that are protected from rewriting, such as
+// mentions and code.
+func words(content string) [][]int {
+	var locs [][]int
+	last := 0
+	protected := append(protectedRegexp.FindAllStringIndex(content, -1), []int{len(content), len(content)})
+	for _, p := range protected {
+		for _, loc := range wordRegexp.FindAllStringIndex(content[last:p[0]], -1) {
+			locs = append(locs, []int{last + loc[0], last + loc[1]})
+		}
+		last = p[1]
+	}
+	return locs
+}
+
+// wordSet builds a set of normalized words from a list of words.
+func wordSet(words []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		set[normalizeWord(w)] = struct{}{}
+	}
+	return set
+}
+
+// New creates a Rule of the given kind. param is interpreted according to the kind of rule:
+//   - prefix: the prefix messages must start with
+//   - allowlist and forbidden: the list of words, separated by commas or spaces
+//   - no-first-person: unused
+//   - max-words: the maximum number of words
+func New(kind Kind, action Action, param string) (Rule, error) {
+	param = strings.TrimSpace(param)
+	switch kind {
+	case KindPrefix:
+		if param == "" {
+			return nil, fmt.Errorf("%w: a prefix is required", ErrInvalidParam)
+		}
+		return &Prefix{Prefix: param, Action: action}, nil
+	case KindAllowlist, KindForbidden:
+		words := strings.FieldsFunc(param, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%w: at least one word is required", ErrInvalidParam)
+		}
+		return &WordList{Words: wordSet(words), Allow: kind == KindAllowlist, Action: action}, nil
+	case KindNoFirstPerson:
+		return &WordList{Words: wordSet(FirstPersonPronouns), Action: action}, nil
+	case KindMaxWords:
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: the maximum number of words must be a positive number", ErrInvalidParam)
+		}
+		return &MaxWords{Max: n, Action: action}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKind, kind)
+	}
+}
+
+// Prefix requires that messages start with a particular prefix, such as a drone ID.
+type Prefix struct {
+	Prefix string
+	Action Action
+}
+
+var _ Rule = (*Prefix)(nil)
+
+func (r *Prefix) Apply(m *Message) error {
+	if strings.HasPrefix(m.Content, r.Prefix) {
+		return nil
+	}
+	return enforce(r.Action, m, "Message must start with "+r.Prefix, func() {
+		m.Content = r.Prefix + " " + m.Content
+	})
+}
+
+// WordList either only allows words from a list, or forbids words from a list.
+type WordList struct {
+	Words map[string]struct{}
+	// Allow is true if only the words in the list are allowed, or false if the words in the list are forbidden.
+	Allow  bool
+	Action Action
+}
+
+var _ Rule = (*WordList)(nil)
+
+func (r *WordList) violates(word string) bool {
+	_, ok := r.Words[normalizeWord(word)]
+	return ok != r.Allow
+}
+
+func (r *WordList) Apply(m *Message) error {
+	locs := words(m.Content)
+	var bad []string
+	for _, loc := range locs {
+		if w := m.Content[loc[0]:loc[1]]; r.violates(w) {
+			bad = append(bad, w)
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+
+	reason := "Forbidden words used: " + strings.Join(bad, ", ")
+	if r.Allow {
+		reason = "Words not permitted: " + strings.Join(bad, ", ")
+	}
+	return enforce(r.Action, m, reason, func() {
+		var sb strings.Builder
+		last := 0
+		for _, loc := range locs {
+			if r.violates(m.Content[loc[0]:loc[1]]) {
+				sb.WriteString(m.Content[last:loc[0]])
+				sb.WriteString(redacted)
+				last = loc[1]
+			}
+		}
+		sb.WriteString(m.Content[last:])
+		m.Content = sb.String()
+	})
+}
+
+// MaxWords limits how many words can be in a message.
+type MaxWords struct {
+	Max    int
+	Action Action
+}
+
+var _ Rule = (*MaxWords)(nil)
+
+func (r *MaxWords) Apply(m *Message) error {
+	locs := words(m.Content)
+	if len(locs) <= r.Max {
+		return nil
+	}
+	return enforce(r.Action, m, fmt.Sprintf("Message is longer than %d words", r.Max), func() {
+		m.Content = m.Content[:locs[r.Max-1][1]]
+	})
+}
@@ -0,0 +1,137 @@
+package speech_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+func TestRules(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		kind   speech.Kind
+		action speech.Action
+		param  string
+		in     string
+		// want is the message after the rule is applied, or "" if it is rejected.
+		want        string
+		annotations []string
+	}{
+		{
+			name: "prefix present",
+			kind: speech.KindPrefix, action: speech.ActionReject, param: "1234:",
+			in: "1234: hello", want: "1234: hello",
+		},
+		{
+			name: "prefix missing",
+			kind: speech.KindPrefix, action: speech.ActionReject, param: "1234:",
+			in: "hello",
+		},
+		{
+			name: "prefix added",
+			kind: speech.KindPrefix, action: speech.ActionRewrite, param: "1234:",
+			in: "hello", want: "1234: hello",
+		},
+		{
+			name: "forbidden word",
+			kind: speech.KindForbidden, action: speech.ActionReject, param: "bad, worse",
+			in: "this is Bad",
+		},
+		{
+			name: "forbidden word redacted",
+			kind: speech.KindForbidden, action: speech.ActionRewrite, param: "bad worse",
+			in: "bad, worse, fine", want: "▇▇▇, ▇▇▇, fine",
+		},
+		{
+			name: "forbidden word annotated",
+			kind: speech.KindForbidden, action: speech.ActionAnnotate, param: "bad",
+			in: "not bad", want: "not bad", annotations: []string{"Forbidden words used: bad"},
+		},
+		{
+			name: "forbidden word in protected spans",
+			kind: speech.KindForbidden, action: speech.ActionReject, param: "bad",
+			in: "see `bad` and <#123> https://example.com/bad <:bad:456>", want: "see `bad` and <#123> https://example.com/bad <:bad:456>",
+		},
+		{
+			name: "forbidden word redacted around protected spans",
+			kind: speech.KindForbidden, action: speech.ActionRewrite, param: "bad",
+			in: "bad `bad` bad", want: "▇▇▇ `bad` ▇▇▇",
+		},
+		{
+			name: "allowlist",
+			kind: speech.KindAllowlist, action: speech.ActionReject, param: "yes,no",
+			in: "Yes. no!", want: "Yes. no!",
+		},
+		{
+			name: "allowlist violated",
+			kind: speech.KindAllowlist, action: speech.ActionRewrite, param: "yes,no",
+			in: "yes maybe <@123>", want: "yes ▇▇▇ <@123>",
+		},
+		{
+			name: "no first person",
+			kind: speech.KindNoFirstPerson, action: speech.ActionRewrite,
+			in: "I’m sure it's mine", want: "▇▇▇ sure it's ▇▇▇",
+		},
+		{
+			name: "max words",
+			kind: speech.KindMaxWords, action: speech.ActionReject, param: "2",
+			in: "one two three",
+		},
+		{
+			name: "max words truncated",
+			kind: speech.KindMaxWords, action: speech.ActionRewrite, param: "2",
+			in: "one, two, three", want: "one, two",
+		},
+		{
+			name: "max words skips protected spans",
+			kind: speech.KindMaxWords, action: speech.ActionRewrite, param: "2",
+			in: "hi <@123> `some long code` there friend", want: "hi <@123> `some long code` there",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := speech.New(tc.kind, tc.action, tc.param)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := &speech.Message{Content: tc.in}
+			err = r.Apply(m)
+
+			var rejected *speech.RejectedError
+			if tc.want == "" {
+				if !errors.As(err, &rejected) {
+					t.Errorf("Apply returned %v, want it rejected", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Apply returned %v", err)
+			}
+			if m.Content != tc.want {
+				t.Errorf("got %q, want %q", m.Content, tc.want)
+			}
+			if !slices.Equal(m.Annotations, tc.annotations) {
+				t.Errorf("got annotations %q, want %q", m.Annotations, tc.annotations)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	for _, tc := range []struct {
+		kind  speech.Kind
+		param string
+		want  error
+	}{
+		{"shout", "", speech.ErrInvalidKind},
+		{speech.KindPrefix, " ", speech.ErrInvalidParam},
+		{speech.KindForbidden, ", ,", speech.ErrInvalidParam},
+		{speech.KindMaxWords, "0", speech.ErrInvalidParam},
+		{speech.KindMaxWords, "lots", speech.ErrInvalidParam},
+	} {
+		_, err := speech.New(tc.kind, speech.ActionReject, tc.param)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("New(%s, %q) returned %v, want %v", tc.kind, tc.param, err, tc.want)
+		}
+	}
+}
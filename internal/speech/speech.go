@@ -0,0 +1,102 @@
+// Package speech implements the rules that are applied to what a Synth says before it is proxied.
+package speech
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Action is what a Rule does when a message violates it.
+type Action string
+
+const (
+	// ActionReject prevents the message from being sent.
+	ActionReject Action = "reject"
+	// ActionRewrite changes the message so that it no longer violates the rule.
+	ActionRewrite Action = "rewrite"
+	// ActionAnnotate sends the message unchanged, with a note about the violation.
+	ActionAnnotate Action = "annotate"
+)
+
+// Actions are all valid Actions.
+var Actions = []Action{ActionReject, ActionRewrite, ActionAnnotate}
+
+// ParseAction parses an Action, defaulting to ActionReject if the string is empty.
+func ParseAction(s string) (Action, error) {
+	if s == "" {
+		return ActionReject, nil
+	}
+	for _, a := range Actions {
+		if strings.EqualFold(s, string(a)) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidAction, s)
+}
+
+var ErrInvalidAction = errors.New("invalid action")
+
+// RejectedError is returned when a message is rejected by a Rule.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "message rejected: " + e.Reason
+}
+
+// Message is a message that is being processed by Rules.
+type Message struct {
+	Content     string
+	Annotations []string
+}
+
+// String renders the message, including any annotations.
+func (m *Message) String() string {
+	var sb strings.Builder
+	sb.WriteString(m.Content)
+	for _, a := range m.Annotations {
+		sb.WriteString("\n-# ")
+		sb.WriteString(a)
+	}
+	return sb.String()
+}
+
+// Rule is a single rule that messages are checked against.
+type Rule interface {
+	// Apply applies the rule to the message, modifying it if needed. A *RejectedError is returned if the message is
+	// rejected.
+	Apply(m *Message) error
+}
+
+// Pipeline is an ordered list of Rules.
+type Pipeline []Rule
+
+var _ Rule = (Pipeline)(nil)
+
+// Apply applies every Rule in order, stopping at the first one that rejects the message.
+func (p Pipeline) Apply(m *Message) error {
+	for _, r := range p {
+		err := r.Apply(m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enforce performs the Action for a rule violation. rewrite is called to fix the message if the Action is
+// ActionRewrite.
+func enforce(a Action, m *Message, reason string, rewrite func()) error {
+	switch a {
+	case ActionRewrite:
+		rewrite()
+		return nil
+	case ActionAnnotate:
+		m.Annotations = append(m.Annotations, reason)
+		return nil
+	default:
+		return &RejectedError{Reason: reason}
+	}
+}
@@ -0,0 +1,104 @@
+package speech
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxMessageLength is the most characters Discord allows a bot to send in a message.
+const MaxMessageLength = 2000
+
+var ErrTooLong = errors.New("message too long")
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// Placeholders are the placeholders that can be used in a Template, and what they are replaced with.
+var Placeholders = map[string]string{
+	"content": "the message",
+	"name":    "the Synth's display name on this server",
+	"id":      "the Synth's username",
+	"owner":   "a mention of the Synth's owner",
+	"energy":  "the Synth's energy after sending the message",
+	"time":    "the time the message was sent",
+}
+
+// placeholderMaxLength is the longest a placeholder other than content can be once it is rendered.
+var placeholderMaxLength = map[string]int{
+	"name":   32,
+	"id":     32,
+	"owner":  23,
+	"energy": 10,
+	"time":   16,
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{([a-z]*)}`)
+
+// TemplateData is the data used to render a Template.
+type TemplateData struct {
+	Content string
+	Name    string
+	ID      string
+	OwnerID string
+	// Energy is the Synth's energy, or nil if energy is not being enforced.
+	Energy *float64
+	Time   time.Time
+}
+
+// Template wraps what a Synth says, such as with a prefix and suffix.
+type Template struct {
+	raw string
+}
+
+// ParseTemplate parses a template, ensuring it only uses known placeholders, includes the content, and leaves enough
+// room for the content.
+func ParseTemplate(s string) (*Template, error) {
+	hasContent := false
+	maxLength := utf8.RuneCountInString(s)
+	for _, match := range placeholderRegexp.FindAllStringSubmatch(s, -1) {
+		name := match[1]
+		if _, ok := Placeholders[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown placeholder %s", ErrInvalidTemplate, match[0])
+		}
+		if name == "content" {
+			hasContent = true
+		}
+		maxLength += placeholderMaxLength[name] - utf8.RuneCountInString(match[0])
+	}
+	if !hasContent {
+		return nil, fmt.Errorf("%w: {content} is required", ErrInvalidTemplate)
+	}
+	// leave at least half of the message for the content
+	if maxLength > MaxMessageLength/2 {
+		return nil, fmt.Errorf("%w: template is too long", ErrInvalidTemplate)
+	}
+	return &Template{raw: s}, nil
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Render renders the template. ErrTooLong is returned if the result would be too long for Discord.
+func (t *Template) Render(d TemplateData) (string, error) {
+	energy := "∞"
+	if d.Energy != nil {
+		energy = strconv.Itoa(int(*d.Energy))
+	}
+	r := strings.NewReplacer(
+		"{content}", d.Content,
+		"{name}", d.Name,
+		"{id}", d.ID,
+		"{owner}", "<@"+d.OwnerID+">",
+		"{energy}", energy,
+		"{time}", fmt.Sprintf("<t:%d:T>", d.Time.Unix()),
+	)
+	ret := r.Replace(t.raw)
+	if utf8.RuneCountInString(ret) > MaxMessageLength {
+		return "", fmt.Errorf("%w: %d characters", ErrTooLong, utf8.RuneCountInString(ret))
+	}
+	return ret, nil
+}
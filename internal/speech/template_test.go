@@ -0,0 +1,69 @@
+package speech_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajanata/synthos/internal/speech"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		// valid is whether the template is accepted.
+		valid bool
+	}{
+		{"content only", "{content}", true},
+		{"every placeholder", "[{time}] {name} ({id}, owned by {owner}, {energy}): {content}", true},
+		{"no content", "{name} says nothing", false},
+		{"unknown placeholder", "{content} {mood}", false},
+		// {content} itself doesn't count toward the template's length
+		{"room for half the message", strings.Repeat("x", speech.MaxMessageLength/2) + "{content}", true},
+		{"too long", strings.Repeat("x", speech.MaxMessageLength/2+1) + "{content}", false},
+		// the placeholders count as long as they can get once they are rendered
+		{"placeholders too long", strings.Repeat("x", speech.MaxMessageLength/2-31) + "{name}{content}", false},
+	} {
+		_, err := speech.ParseTemplate(tc.in)
+		if tc.valid && err != nil {
+			t.Errorf("%s: returned %v", tc.name, err)
+		} else if !tc.valid && !errors.Is(err, speech.ErrInvalidTemplate) {
+			t.Errorf("%s: returned %v, want it invalid", tc.name, err)
+		}
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl, err := speech.ParseTemplate("{id} ({energy}) to {owner} at {time}: {content}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	energy := 41.9
+	d := speech.TemplateData{
+		Content: "hello {name}",
+		Name:    "Drone",
+		ID:      "drone-1234",
+		OwnerID: "42",
+		Energy:  &energy,
+		Time:    time.Unix(1700000000, 0),
+	}
+
+	// placeholders in the content are left alone
+	got, err := tmpl.Render(d)
+	if want := "drone-1234 (41) to <@42> at <t:1700000000:T>: hello {name}"; err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+
+	d.Energy = nil
+	got, err = tmpl.Render(d)
+	if want := "drone-1234 (∞) to <@42> at <t:1700000000:T>: hello {name}"; err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+
+	d.Content = strings.Repeat("é", speech.MaxMessageLength)
+	_, err = tmpl.Render(d)
+	if !errors.Is(err, speech.ErrTooLong) {
+		t.Errorf("returned %v, want it too long", err)
+	}
+}
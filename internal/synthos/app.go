@@ -7,18 +7,23 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/ajanata/synthos/internal/bots/controller"
-	"github.com/ajanata/synthos/internal/bots/synth"
+	"github.com/ajanata/synthos/internal/bots/validator"
 	"github.com/ajanata/synthos/internal/config"
 	"github.com/ajanata/synthos/internal/database"
 )
 
+// synthPageSize is how many Synths are loaded from the database at a time when starting.
+const synthPageSize = 100
+
 type App struct {
 	config config.Config
 	db     *database.DB
 	close  chan struct{}
 
 	controller *controller.Bot
-	synths     map[string]*synth.Bot
+	supervisor *supervisor
+	// getAppID validates a token, and gets the ID of the application it is for. It is replaced in tests.
+	getAppID func(ctx context.Context, token string) (string, error)
 }
 
 func New(c config.Config, db *database.DB) *App {
@@ -26,6 +31,9 @@ func New(c config.Config, db *database.DB) *App {
 		config: c,
 		db:     db,
 		close:  make(chan struct{}),
+
+		supervisor: newSupervisor(c.SynthOS),
+		getAppID:   validator.GetAppID,
 	}
 }
 
@@ -36,7 +44,7 @@ func (app *App) Run() error {
 	defer app.stop()
 
 	log.Trace().Msg("Starting controller")
-	app.controller = controller.New(app.config.SynthOS.Controller, app)
+	app.controller = controller.New(app.config.SynthOS, app)
 	err := app.controller.Start()
 	if err != nil {
 		return fmt.Errorf("starting controller: %w", err)
@@ -44,19 +52,20 @@ func (app *App) Run() error {
 	log.Info().Msg("Controller started")
 
 	log.Trace().Msg("Starting synths")
-	app.synths = make(map[string]*synth.Bot)
-	synths, err := app.db.GetEnabledSynths(context.Background())
-	if err != nil {
-		return fmt.Errorf("getting enabled synths: %w", err)
-	}
-	for _, s := range synths {
-		sb := synth.New(s)
-		err := sb.Start()
+	var after uint64
+	for {
+		synths, err := app.db.GetEnabledSynths(context.Background(), after, synthPageSize)
 		if err != nil {
-			log.Error().Err(err).Str("user_id", s.DiscordUserID).Msg("starting synth")
-			continue
+			return fmt.Errorf("getting enabled synths: %w", err)
 		}
-		app.synths[s.DiscordUserID] = sb
+		for _, s := range synths {
+			// a Synth that fails to start is logged and restarted later by the supervisor
+			_ = app.supervisor.Start(context.Background(), s, nil)
+		}
+		if len(synths) < synthPageSize {
+			break
+		}
+		after = synths[len(synths)-1].ID
 	}
 	log.Info().Msg("Synths started")
 
@@ -76,16 +85,9 @@ func (app *App) stop() {
 	// while stopping stuff, we want to stop _everything_ even if we get some errors, so we directly log the errors here
 	// instead of returning them to our caller
 
-	if app.synths != nil {
-		log.Trace().Msg("Stopping synths")
-		for uid, sb := range app.synths {
-			err := sb.Close()
-			if err != nil {
-				log.Error().Err(err).Str("user_id", uid).Msg("closing synth")
-			}
-		}
-		log.Info().Msg("Synths stopped")
-	}
+	log.Trace().Msg("Stopping synths")
+	app.supervisor.StopAll()
+	log.Info().Msg("Synths stopped")
 
 	if app.controller != nil {
 		log.Trace().Msg("Stopping controller")
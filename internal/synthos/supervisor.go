@@ -0,0 +1,346 @@
+package synthos
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajanata/synthos/internal/bots"
+	"github.com/ajanata/synthos/internal/bots/synth"
+	"github.com/ajanata/synthos/internal/config"
+	"github.com/ajanata/synthos/internal/database"
+)
+
+const (
+	// reconnectDelay is how long to wait before reconnecting a Synth that lost its connection to Discord.
+	reconnectDelay = time.Second
+	// minRestartDelay is how long to wait before restarting a Synth that failed. It doubles with each failure in a row,
+	// up to maxRestartDelay.
+	minRestartDelay = 5 * time.Second
+	maxRestartDelay = 10 * time.Minute
+)
+
+// synthBot is the part of a synth.Bot that the supervisor uses.
+type synthBot interface {
+	Start(progress bots.BootProgress) error
+	Watch(watcher func(state bots.SynthState))
+	Reconnect() error
+	SwapToken(ctx context.Context, appID, token string) error
+	Close() error
+	Delete(ctx context.Context) error
+}
+
+// supervisor runs Synths, tracking their health. Synths that lose their connection to Discord are reconnected, and
+// Synths that fail to start or reconnect are restarted with exponential backoff, until they are removed.
+type supervisor struct {
+	config config.SynthOS
+	// newBot makes the bot for a Synth. It is replaced in tests.
+	newBot func(c config.SynthOS, s *database.Synth) synthBot
+
+	reconnectDelay  time.Duration
+	minRestartDelay time.Duration
+	maxRestartDelay time.Duration
+
+	mu     sync.Mutex
+	synths map[string]*supervised
+}
+
+// supervised is a Synth being run by the supervisor.
+type supervised struct {
+	synth *database.Synth
+
+	// opMu serializes starting, reconnecting, and stopping the bot. Those can take a while, so they are not done while
+	// holding supervisor.mu.
+	opMu sync.Mutex
+
+	// the rest are guarded by supervisor.mu, and bot is only replaced while also holding opMu
+
+	bot    synthBot
+	status bots.SynthStatus
+	// retry reconnects or restarts the Synth, if one is pending.
+	retry *time.Timer
+	// stopped is set once the Synth is removed, after which nothing else is done with it.
+	stopped bool
+}
+
+func newSupervisor(c config.SynthOS) *supervisor {
+	return &supervisor{
+		config: c,
+		newBot: func(c config.SynthOS, s *database.Synth) synthBot {
+			return synth.New(c, s)
+		},
+		reconnectDelay:  reconnectDelay,
+		minRestartDelay: minRestartDelay,
+		maxRestartDelay: maxRestartDelay,
+		synths:          make(map[string]*supervised),
+	}
+}
+
+// Start starts a Synth and supervises it, reporting its progress to progress, which may be nil. If the Synth was
+// already being supervised, the old bot is closed first. If it fails to start, the error is returned, and it will be
+// restarted later.
+func (sup *supervisor) Start(ctx context.Context, s *database.Synth, progress bots.BootProgress) error {
+	old := sup.Remove(s.DiscordUserID)
+	if old != nil {
+		err := old.Close()
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("closing old synth")
+		}
+	}
+
+	sv := &supervised{synth: s}
+	sv.opMu.Lock()
+	defer sv.opMu.Unlock()
+	sup.mu.Lock()
+	sup.synths[s.DiscordUserID] = sv
+	sup.mu.Unlock()
+
+	return sup.start(sv, progress)
+}
+
+// start makes a new bot for a Synth and starts it. sv.opMu must be held.
+func (sup *supervisor) start(sv *supervised, progress bots.BootProgress) error {
+	bot := sup.newBot(sup.config, sv.synth)
+	bot.Watch(func(state bots.SynthState) {
+		sup.changed(sv, bot, state)
+	})
+
+	sup.mu.Lock()
+	sv.bot = bot
+	sup.set(sv, bots.SynthStarting)
+	sup.mu.Unlock()
+
+	err := bot.Start(progress)
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if err != nil {
+		sup.fail(sv, err)
+		return err
+	}
+	sup.set(sv, bots.SynthReady)
+	return nil
+}
+
+// changed handles a bot reporting that its connection to Discord was lost or regained.
+func (sup *supervisor) changed(sv *supervised, bot synthBot, state bots.SynthState) {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	// the old bot is disconnected when it is replaced, which is expected
+	if sv.stopped || sv.bot != bot {
+		return
+	}
+
+	// while starting or after failing, whatever is starting it will set the state when it's done
+	switch {
+	case state == bots.SynthDegraded && sv.status.State == bots.SynthReady:
+		log.Warn().Str("user_id", sv.synth.DiscordUserID).Msg("Synth lost its connection; reconnecting")
+		sup.set(sv, bots.SynthDegraded)
+		sv.retry = time.AfterFunc(sup.reconnectDelay, func() {
+			sup.reconnect(sv)
+		})
+	case state == bots.SynthReady && sv.status.State == bots.SynthDegraded:
+		sup.set(sv, bots.SynthReady)
+	}
+}
+
+// reconnect reconnects a Synth that lost its connection to Discord.
+func (sup *supervisor) reconnect(sv *supervised) {
+	sv.opMu.Lock()
+	defer sv.opMu.Unlock()
+
+	sup.mu.Lock()
+	if sv.stopped || sv.status.State != bots.SynthDegraded {
+		sup.mu.Unlock()
+		return
+	}
+	bot := sv.bot
+	sup.mu.Unlock()
+
+	err := bot.Reconnect()
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if err != nil {
+		sup.fail(sv, err)
+		return
+	}
+	sup.set(sv, bots.SynthReady)
+}
+
+// restart replaces a failed Synth's bot with a new one, and starts it.
+func (sup *supervisor) restart(sv *supervised) {
+	sv.opMu.Lock()
+	defer sv.opMu.Unlock()
+
+	sup.mu.Lock()
+	if sv.stopped {
+		sup.mu.Unlock()
+		return
+	}
+	old := sv.bot
+	sup.mu.Unlock()
+
+	log.Info().Str("user_id", sv.synth.DiscordUserID).Msg("Restarting synth")
+	err := old.Close()
+	if err != nil {
+		log.Err(err).Str("user_id", sv.synth.DiscordUserID).Msg("closing failed synth")
+	}
+	// a failure is already logged, and will be retried
+	_ = sup.start(sv, nil)
+}
+
+// set changes the state of a Synth. supervisor.mu must be held.
+func (sup *supervisor) set(sv *supervised, state bots.SynthState) {
+	if sv.stopped || sv.status.State == state {
+		return
+	}
+	sv.status.State = state
+	sv.status.Since = time.Now()
+	if state == bots.SynthReady {
+		sv.status.Failures = 0
+		sv.status.Err = nil
+		sv.status.Retry = time.Time{}
+	}
+}
+
+// fail marks a Synth as failed, and schedules it to be restarted. supervisor.mu must be held.
+func (sup *supervisor) fail(sv *supervised, err error) {
+	if sv.stopped {
+		return
+	}
+	sup.set(sv, bots.SynthFailed)
+	sv.status.Failures++
+	sv.status.Err = err
+	delay := sup.backoff(sv.status.Failures)
+	sv.status.Retry = time.Now().Add(delay)
+	sv.retry = time.AfterFunc(delay, func() {
+		sup.restart(sv)
+	})
+
+	log.Error().Err(err).
+		Str("user_id", sv.synth.DiscordUserID).
+		Int("failures", sv.status.Failures).
+		Dur("retry_in", delay).
+		Msg("Synth failed")
+}
+
+// backoff is how long to wait before restarting a Synth that has failed failures times in a row.
+func (sup *supervisor) backoff(failures int) time.Duration {
+	delay := sup.minRestartDelay
+	for range failures - 1 {
+		delay *= 2
+		if delay >= sup.maxRestartDelay {
+			return sup.maxRestartDelay
+		}
+	}
+	return delay
+}
+
+// SwapToken gives a running Synth a new token, keeping everything else about it. It returns false without doing
+// anything if the Synth isn't connected to Discord, in which case it should be started again instead.
+func (sup *supervisor) SwapToken(ctx context.Context, userID, appID, token string) (bool, error) {
+	sup.mu.Lock()
+	sv, ok := sup.synths[userID]
+	sup.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	sv.opMu.Lock()
+	defer sv.opMu.Unlock()
+
+	sup.mu.Lock()
+	if sv.stopped || (sv.status.State != bots.SynthReady && sv.status.State != bots.SynthDegraded) {
+		sup.mu.Unlock()
+		return false, nil
+	}
+	bot := sv.bot
+	if sv.retry != nil {
+		sv.retry.Stop()
+	}
+	sup.mu.Unlock()
+
+	err := bot.SwapToken(ctx, appID, token)
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if err != nil {
+		sup.fail(sv, err)
+		return true, err
+	}
+	sup.set(sv, bots.SynthReady)
+	return true, nil
+}
+
+// Remove stops supervising a Synth, and returns its bot so that it can be closed or deleted. It returns nil if the Synth
+// wasn't being supervised.
+func (sup *supervisor) Remove(userID string) synthBot {
+	sup.mu.Lock()
+	sv, ok := sup.synths[userID]
+	if !ok {
+		sup.mu.Unlock()
+		return nil
+	}
+	delete(sup.synths, userID)
+	sup.stop(sv)
+	sup.mu.Unlock()
+
+	// wait for anything that was already being done with it to finish
+	sv.opMu.Lock()
+	defer sv.opMu.Unlock()
+	return sv.bot
+}
+
+// stop marks a Synth as stopped, so that nothing else is done with it. supervisor.mu must be held.
+func (sup *supervisor) stop(sv *supervised) {
+	sup.set(sv, bots.SynthStopped)
+	sv.stopped = true
+	if sv.retry != nil {
+		sv.retry.Stop()
+	}
+}
+
+// StopAll stops and closes every Synth.
+func (sup *supervisor) StopAll() {
+	sup.mu.Lock()
+	synths := sup.synths
+	sup.synths = make(map[string]*supervised)
+	for _, sv := range synths {
+		sup.stop(sv)
+	}
+	sup.mu.Unlock()
+
+	for uid, sv := range synths {
+		sv.opMu.Lock()
+		err := sv.bot.Close()
+		sv.opMu.Unlock()
+		if err != nil {
+			log.Error().Err(err).Str("user_id", uid).Msg("closing synth")
+		}
+	}
+}
+
+// Status gets the status of a Synth, and whether it is being supervised.
+func (sup *supervisor) Status(userID string) (bots.SynthStatus, bool) {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	sv, ok := sup.synths[userID]
+	if !ok {
+		return bots.SynthStatus{State: bots.SynthStopped}, false
+	}
+	return sv.status, true
+}
+
+// Statuses gets the status of every Synth being supervised, by the ID of its owner.
+func (sup *supervisor) Statuses() map[string]bots.SynthStatus {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	statuses := make(map[string]bots.SynthStatus, len(sup.synths))
+	for uid, sv := range sup.synths {
+		statuses[uid] = sv.status
+	}
+	return statuses
+}
@@ -0,0 +1,212 @@
+package synthos
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajanata/synthos/internal/bots"
+	"github.com/ajanata/synthos/internal/config"
+	"github.com/ajanata/synthos/internal/database"
+)
+
+var errFake = errors.New("fake failure")
+
+// fakeBot is a synthBot that fails as it is told to.
+type fakeBot struct {
+	mu           sync.Mutex
+	startErr     error
+	reconnectErr error
+	swapErr      error
+	watcher      func(state bots.SynthState)
+	reconnects   int
+	closed       bool
+}
+
+func (f *fakeBot) Start(progress bots.BootProgress) error {
+	progress.Report(bots.BootReady)
+	return f.startErr
+}
+
+func (f *fakeBot) Watch(watcher func(state bots.SynthState)) {
+	f.watcher = watcher
+}
+
+func (f *fakeBot) Reconnect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reconnects++
+	return f.reconnectErr
+}
+
+func (f *fakeBot) SwapToken(context.Context, string, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.swapErr
+}
+
+func (f *fakeBot) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeBot) Delete(context.Context) error {
+	return f.Close()
+}
+
+// fakeBots makes fakeBots for a supervisor, keeping each one it made.
+type fakeBots struct {
+	mu   sync.Mutex
+	made []*fakeBot
+	// startErrs are returned by the Start of each bot in turn, and then nil.
+	startErrs []error
+	// swapErr is returned by the SwapToken of every bot.
+	swapErr error
+}
+
+func (f *fakeBots) newBot(config.SynthOS, *database.Synth) synthBot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bot := &fakeBot{swapErr: f.swapErr}
+	if len(f.made) < len(f.startErrs) {
+		bot.startErr = f.startErrs[len(f.made)]
+	}
+	f.made = append(f.made, bot)
+	return bot
+}
+
+func (f *fakeBots) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.made)
+}
+
+func (f *fakeBots) bot(n int) *fakeBot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.made[n]
+}
+
+// newTestSupervisor makes a supervisor that doesn't wait long to reconnect or restart Synths.
+func newTestSupervisor(startErrs ...error) (*supervisor, *fakeBots) {
+	made := &fakeBots{startErrs: startErrs}
+	sup := newSupervisor(config.SynthOS{})
+	sup.newBot = made.newBot
+	sup.reconnectDelay = time.Millisecond
+	sup.minRestartDelay = time.Millisecond
+	sup.maxRestartDelay = 4 * time.Millisecond
+	return sup, made
+}
+
+// waitForState waits for a Synth to reach a state.
+func waitForState(t *testing.T, sup *supervisor, userID string, state bots.SynthState) bots.SynthStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := sup.Status(userID)
+		if status.State == state {
+			return status
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Synth is %s, want %s", status.State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisorRestartsFailedSynths(t *testing.T) {
+	sup, made := newTestSupervisor(errFake, errFake)
+	defer sup.StopAll()
+
+	var reached []bots.BootStage
+	err := sup.Start(t.Context(), &database.Synth{DiscordUserID: "owner"}, func(stage bots.BootStage) {
+		reached = append(reached, stage)
+	})
+	if !errors.Is(err, errFake) {
+		t.Errorf("Start returned %v, want the failure", err)
+	}
+	if len(reached) != 1 {
+		t.Errorf("progress was reported %d times, want 1", len(reached))
+	}
+
+	status := waitForState(t, sup, "owner", bots.SynthReady)
+	if made.count() != 3 {
+		t.Errorf("made %d bots, want 3", made.count())
+	}
+	if !made.bot(0).closed || !made.bot(1).closed || made.bot(2).closed {
+		t.Error("want only the failed bots closed")
+	}
+	if status.Failures != 0 || status.Err != nil {
+		t.Errorf("status is %+v, want the failures cleared", status)
+	}
+}
+
+func TestSupervisorReconnects(t *testing.T) {
+	sup, made := newTestSupervisor()
+	defer sup.StopAll()
+
+	err := sup.Start(t.Context(), &database.Synth{DiscordUserID: "owner"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := made.bot(0)
+	bot.watcher(bots.SynthDegraded)
+	waitForState(t, sup, "owner", bots.SynthReady)
+	bot.mu.Lock()
+	if bot.reconnects != 1 {
+		t.Errorf("reconnected %d times, want 1", bot.reconnects)
+	}
+	// the next reconnect fails, so it will be restarted instead
+	bot.reconnectErr = errFake
+	bot.mu.Unlock()
+
+	bot.watcher(bots.SynthDegraded)
+	waitForState(t, sup, "owner", bots.SynthReady)
+	if made.count() != 2 || !bot.closed {
+		t.Errorf("made %d bots, want the one that failed to reconnect replaced", made.count())
+	}
+}
+
+func TestSupervisorRemove(t *testing.T) {
+	sup, made := newTestSupervisor(errFake, errFake, errFake)
+	sup.minRestartDelay = 10 * time.Millisecond
+
+	_ = sup.Start(t.Context(), &database.Synth{DiscordUserID: "owner"}, nil)
+	if status, _ := sup.Status("owner"); status.State != bots.SynthFailed || status.Retry.IsZero() {
+		t.Errorf("status is %+v, want it failed and waiting to restart", status)
+	}
+
+	if sup.Remove("owner") != made.bot(0) {
+		t.Error("Remove did not return the bot")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if made.count() != 1 {
+		t.Errorf("made %d bots, want no restarts after being removed", made.count())
+	}
+	if _, ok := sup.Status("owner"); ok {
+		t.Error("the Synth is still supervised")
+	}
+	if sup.Remove("owner") != nil {
+		t.Error("removed a Synth that wasn't supervised")
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	sup := newSupervisor(config.SynthOS{})
+	for failures, want := range map[int]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		3:  20 * time.Second,
+		7:  320 * time.Second,
+		8:  10 * time.Minute,
+		50: 10 * time.Minute,
+	} {
+		if got := sup.backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
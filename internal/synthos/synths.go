@@ -2,13 +2,13 @@ package synthos
 
 import (
 	"context"
+	"errors"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ajanata/synthos/internal/bots"
 	"github.com/ajanata/synthos/internal/bots/controller"
-	"github.com/ajanata/synthos/internal/bots/synth"
-	"github.com/ajanata/synthos/internal/bots/validator"
 	"github.com/ajanata/synthos/internal/database"
 )
 
@@ -16,7 +16,7 @@ func (app *App) CreateSynth(ctx context.Context, u *discordgo.User, token string
 	ctx = log.Ctx(ctx).With().Str("user_id", u.ID).Logger().WithContext(ctx)
 	log.Ctx(ctx).Trace().Msg("CreateSynth")
 
-	id, err := validator.GetAppID(ctx, token)
+	id, err := app.getAppID(ctx, token)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg("invalid token")
 		return controller.ErrInvalidToken
@@ -32,19 +32,178 @@ func (app *App) GetSynth(ctx context.Context, u *discordgo.User) (*database.Synt
 	return app.db.GetSynth(ctx, u.ID)
 }
 
-func (app *App) StartSynth(ctx context.Context, u *discordgo.User) error {
+// StartSynth starts a user's Synth, reporting its progress to progress, which may be nil.
+func (app *App) StartSynth(ctx context.Context, u *discordgo.User, progress bots.BootProgress) error {
 	s, err := app.db.GetSynth(ctx, u.ID)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg("failed to load synth")
 		return controller.ErrUnableToStartSynth
 	}
 
-	sb := synth.New(s)
-	err = sb.Start()
+	// the supervisor logs the error, and will keep trying to start it
+	err = app.supervisor.Start(ctx, s, progress)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("failed to start synth")
 		return controller.ErrUnableToStartSynth
 	}
-	app.synths[u.ID] = sb
 	return nil
 }
+
+// DeleteSynth stops a user's Synth, removes its commands from Discord, and deletes it and all of its settings.
+// database.ErrNotFound is returned if the user does not have a Synth.
+func (app *App) DeleteSynth(ctx context.Context, u *discordgo.User) error {
+	ctx = log.Ctx(ctx).With().Str("user_id", u.ID).Logger().WithContext(ctx)
+	log.Ctx(ctx).Trace().Msg("DeleteSynth")
+
+	// the Synth is only stopped once it is gone, so that it keeps running if it can't be deleted
+	err := app.db.DeleteSynth(ctx, u.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		return err
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to delete synth")
+		return controller.ErrUnableToDeleteSynth
+	}
+
+	sb := app.supervisor.Remove(u.ID)
+	if sb != nil {
+		// the Synth has been deleted regardless, so its commands being left behind is not fatal
+		err = sb.Delete(ctx)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("failed to stop synth")
+		}
+	}
+	return nil
+}
+
+// UpdateToken changes the token of a user's Synth, such as after they reset it, and reconnects the Synth with it. The
+// token must be for the Synth's application, unless rebind is set to move the Synth to another application.
+// database.ErrNotFound is returned if the user does not have a Synth, and controller.ErrTokenNotSwapped if the Synth
+// couldn't reconnect with the token, in which case it keeps the old one.
+func (app *App) UpdateToken(ctx context.Context, u *discordgo.User, token string, rebind bool) error {
+	ctx = log.Ctx(ctx).With().Str("user_id", u.ID).Logger().WithContext(ctx)
+	log.Ctx(ctx).Trace().Msg("UpdateToken")
+
+	s, err := app.db.GetSynth(ctx, u.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		return err
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to load synth")
+		return controller.ErrUnableToUpdateToken
+	}
+
+	id, err := app.getAppID(ctx, token)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("invalid token")
+		return controller.ErrInvalidToken
+	}
+	if id != s.ApplicationID && !rebind {
+		log.Ctx(ctx).Info().Str("old_app_id", s.ApplicationID).Str("new_app_id", id).Msg("token is for another application")
+		return controller.ErrDifferentApplication
+	}
+
+	// the token is saved first, so that a Synth that isn't running starts with it
+	oldAppID, oldToken := s.ApplicationID, s.Token
+	err = s.SetToken(ctx, id, token)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to save token")
+		return controller.ErrUnableToUpdateToken
+	}
+
+	swapped, err := app.supervisor.SwapToken(ctx, u.ID, id, token)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to reconnect synth with new token")
+		// the Synth went back to the old token, so it must still be the one it is restarted with
+		rollbackErr := s.SetToken(ctx, oldAppID, oldToken)
+		if rollbackErr != nil {
+			log.Ctx(ctx).Err(rollbackErr).Msg("failed to restore old token")
+		}
+		return controller.ErrTokenNotSwapped
+	} else if swapped {
+		return nil
+	} else if !s.Enabled {
+		// it will use the new token when it is resumed
+		return nil
+	}
+	// it may not be connected because the old token stopped working
+	return app.StartSynth(ctx, u, nil)
+}
+
+// PauseSynth disables a user's Synth and stops it, without deleting it. database.ErrNotFound is returned if the user
+// does not have a Synth.
+func (app *App) PauseSynth(ctx context.Context, u *discordgo.User) error {
+	ctx = log.Ctx(ctx).With().Str("user_id", u.ID).Logger().WithContext(ctx)
+	log.Ctx(ctx).Trace().Msg("PauseSynth")
+
+	s, err := app.db.GetSynth(ctx, u.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		return err
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to load synth")
+		return controller.ErrUnableToPauseSynth
+	} else if !s.Enabled {
+		return controller.ErrAlreadyPaused
+	}
+
+	err = s.SetEnabled(ctx, false)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to disable synth")
+		return controller.ErrUnableToPauseSynth
+	}
+
+	sb := app.supervisor.Remove(u.ID)
+	if sb != nil {
+		err = sb.Close()
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("failed to stop synth")
+		}
+	}
+	return nil
+}
+
+// ResumeSynth enables a user's paused Synth and starts it. database.ErrNotFound is returned if the user does not have a
+// Synth.
+func (app *App) ResumeSynth(ctx context.Context, u *discordgo.User) error {
+	ctx = log.Ctx(ctx).With().Str("user_id", u.ID).Logger().WithContext(ctx)
+	log.Ctx(ctx).Trace().Msg("ResumeSynth")
+
+	s, err := app.db.GetSynth(ctx, u.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		return err
+	} else if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to load synth")
+		return controller.ErrUnableToStartSynth
+	}
+
+	// resuming an enabled Synth that failed is a way to restart it without waiting
+	status, _ := app.supervisor.Status(u.ID)
+	if s.Enabled && status.State != bots.SynthFailed && status.State != bots.SynthStopped {
+		return controller.ErrNotPaused
+	}
+
+	err = s.SetEnabled(ctx, true)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("failed to enable synth")
+		return controller.ErrUnableToStartSynth
+	}
+	return app.StartSynth(ctx, u, nil)
+}
+
+// SynthStatus gets the health of a user's Synth. database.ErrNotFound is returned if the user does not have a Synth.
+func (app *App) SynthStatus(ctx context.Context, u *discordgo.User) (bots.SynthStatus, error) {
+	ctx = log.Ctx(ctx).With().Str("user_id", u.ID).Logger().WithContext(ctx)
+	log.Ctx(ctx).Trace().Msg("SynthStatus")
+
+	// the Synth isn't supervised if it is paused, but it still exists
+	_, err := app.db.GetSynth(ctx, u.ID)
+	if err != nil {
+		return bots.SynthStatus{}, err
+	}
+	status, _ := app.supervisor.Status(u.ID)
+	return status, nil
+}
+
+// SynthStatuses gets the health of every Synth that is running, by the ID of its owner.
+func (app *App) SynthStatuses(ctx context.Context) map[string]bots.SynthStatus {
+	log.Ctx(ctx).Trace().Msg("SynthStatuses")
+
+	return app.supervisor.Statuses()
+}
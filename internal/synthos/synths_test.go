@@ -0,0 +1,78 @@
+package synthos
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ajanata/synthos/internal/bots/controller"
+	"github.com/ajanata/synthos/internal/config"
+	"github.com/ajanata/synthos/internal/database"
+	"github.com/ajanata/synthos/internal/discord/discordtest"
+)
+
+// newTestApp makes an App backed by a fresh database, with a supervisor of fake bots, that accepts any token for
+// "app-id".
+func newTestApp(t *testing.T) (*App, *fakeBots) {
+	t.Helper()
+	db, err := database.New(config.Database{
+		DBDriver: config.Sqlite3DBDriver,
+		DSN:      filepath.Join(t.TempDir(), "synthos.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sup, made := newTestSupervisor()
+	t.Cleanup(sup.StopAll)
+
+	app := New(config.Config{}, db)
+	app.supervisor = sup
+	app.getAppID = func(context.Context, string) (string, error) {
+		return "app-id", nil
+	}
+	return app, made
+}
+
+func TestUpdateTokenFailedSwap(t *testing.T) {
+	app, made := newTestApp(t)
+	u := discordtest.NewUser("owner")
+	err := app.CreateSynth(t.Context(), u, "old-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.StartSynth(t.Context(), u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	made.bot(0).mu.Lock()
+	made.bot(0).swapErr = errFake
+	made.bot(0).mu.Unlock()
+	err = app.UpdateToken(t.Context(), u, "new-token", false)
+	if !errors.Is(err, controller.ErrTokenNotSwapped) {
+		t.Errorf("UpdateToken returned %v, want the token not swapped", err)
+	}
+
+	// the Synth went back to the old token, so it is the one it must be restarted with
+	s, err := app.GetSynth(t.Context(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Token != "old-token" {
+		t.Errorf("saved token is %q, want the old token", s.Token)
+	}
+
+	// the failed Synth is restarted by a new bot, which can use the new token
+	err = app.UpdateToken(t.Context(), u, "new-token", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = app.GetSynth(t.Context(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Token != "new-token" {
+		t.Errorf("saved token is %q, want the new token", s.Token)
+	}
+}